@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/ttacon/autumn/lib/engine"
+	"github.com/ttacon/autumn/lib/engine/retriever"
+	"github.com/ttacon/autumn/lib/generator"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errGeneratedCodeOutOfDate = errors.New("generated code is out of date")
+)
+
+func apply(c *cli.Context) error {
+	// Apply steps:
+	//
+	//  1. Load config.
+	//  2. Load the retrieved frameworks.
+	//  3. Identify model targets, narrowed down by targeting mode.
+	//  4. Load the plan, or create one if none was given.
+	//  5. Render every planned file in memory.
+	//  6. Either write the files to disk or, in dry-run mode, report how
+	//     they differ from what is on disk.
+
+	cwd, err := enterProjectRoot()
+	if err != nil {
+		return err
+	}
+	root := os.DirFS(cwd)
+
+	conf, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	gener8r, err := loadGenerator(cwd, conf)
+	if err != nil {
+		return err
+	}
+
+	targets, err := identifyTargets(c, cwd, conf)
+	if err != nil {
+		return err
+	}
+
+	var plan *generator.Plan
+	if planFileName := userFlagPath(c, "plan"); len(planFileName) > 0 {
+		planFile, err := os.Open(planFileName)
+		if err != nil {
+			fmt.Println("failed to read plan: ", err)
+			return err
+		}
+		defer planFile.Close()
+
+		if plan, err = generator.ReadPlan(planFile); err != nil {
+			fmt.Println("failed to load plan: ", err)
+			return err
+		}
+		plan.Models = selectPlannedModels(plan.Models, targets)
+	} else if plan, err = gener8r.CreatePlan(targets); err != nil {
+		return err
+	}
+
+	files, err := gener8r.RenderPlan(plan, targets)
+	if err != nil {
+		return err
+	}
+
+	// Writing a patch file implies that we're doing a dry run.
+	patchFileName := userFlagPath(c, "patch")
+	if !c.Bool("dry-run") && len(patchFileName) == 0 {
+		return generator.Store(cwd, files)
+	}
+
+	patch, err := generator.Diff(root, files)
+	if err != nil {
+		return err
+	}
+
+	if len(patchFileName) > 0 {
+		if err := ioutil.WriteFile(patchFileName, patch, 0644); err != nil {
+			fmt.Println("failed to write patch file: ", err)
+			return err
+		}
+	} else if _, err := os.Stdout.Write(patch); err != nil {
+		return err
+	}
+
+	// Exit non-zero so that dry runs can be used to check that generated
+	// code is up to date.
+	if len(patch) > 0 {
+		return errGeneratedCodeOutOfDate
+	}
+	return nil
+}
+
+// selectPlannedModels returns the planned models that are among the given
+// targets, so that targeting mode also applies to applying a plan.
+func selectPlannedModels(
+	models []generator.ModelPlan,
+	targets []engine.ModelTarget,
+) []generator.ModelPlan {
+	var selected = make(map[string]bool)
+	for _, target := range targets {
+		selected[target.Key()] = true
+	}
+
+	var planned []generator.ModelPlan
+	for _, model := range models {
+		if selected[model.Key()] {
+			planned = append(planned, model)
+		}
+	}
+	return planned
+}
+
+// loadGenerator loads the configured frameworks of the project in cwd and
+// returns a generator that renders them.
+func loadGenerator(cwd string, conf config.Config) (generator.Generator, error) {
+	frameworks := configuredFrameworks(conf)
+
+	source, overrides, err := retriever.LoadFrameworkSource(
+		os.DirFS(cwd),
+		frameworks...,
+	)
+	if err != nil {
+		fmt.Println("failed to load frameworks: ", err)
+		return nil, err
+	}
+	reportOverrides(overrides)
+
+	commits, err := retriever.ResolveCommits(cwd, frameworks...)
+	if err != nil {
+		fmt.Println("failed to resolve framework versions: ", err)
+		return nil, err
+	}
+
+	return generator.NewGenerator(conf, source, commits), nil
+}
+
+// reportOverrides lets the user know which framework templates the project
+// overrides. This is written to stderr so that it doesn't get mixed up with
+// any diff that we print.
+func reportOverrides(overrides []retriever.Override) {
+	for _, override := range overrides {
+		kind := "template"
+		if override.Partial {
+			kind = "partial"
+		}
+
+		if override.Stale {
+			fmt.Fprintf(
+				os.Stderr,
+				"warning: %s %q of %s is overridden but %s no longer has a %s with that name\n",
+				kind,
+				override.Name,
+				override.Framework,
+				override.Framework,
+				kind,
+			)
+			continue
+		}
+		fmt.Fprintf(
+			os.Stderr,
+			"using project override for %s %q of %s\n",
+			kind,
+			override.Name,
+			override.Framework,
+		)
+	}
+}
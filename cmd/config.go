@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/ttacon/autumn/lib/engine/retriever"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errUnknownConfigCommand = errors.New("unknown config command")
+	errInvalidConfig        = errors.New("autumn config is invalid")
+)
+
+func configCommand(c *cli.Context) error {
+	if _, err := enterProjectRoot(); err != nil {
+		return err
+	}
+
+	switch subcommand := c.Args().First(); subcommand {
+	case "validate":
+		return validate(c)
+	case "show":
+		return show(c)
+	default:
+		return fmt.Errorf("%w %q, expected one of: validate, show", errUnknownConfigCommand, subcommand)
+	}
+}
+
+func validate(c *cli.Context) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	fmt.Println("autumn config is valid")
+	return nil
+}
+
+// show prints every value of the effective config, and with --origin where
+// each one came from.
+func show(c *cli.Context) error {
+	flags := flag.NewFlagSet("show", flag.ContinueOnError)
+	withOrigin := flags.Bool("origin", false, "show where each value came from")
+	if err := flags.Parse(c.Args().Tail()); err != nil {
+		return err
+	}
+
+	loaded, err := loadLayeredConfig()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, value := range loaded.Values() {
+		if *withOrigin {
+			fmt.Fprintf(w, "%s = %s\t# %s\n", value.Key, value.Format(), value.Origin)
+		} else {
+			fmt.Fprintf(w, "%s = %s\n", value.Key, value.Format())
+		}
+	}
+	return w.Flush()
+}
+
+// configRoots returns the roots that config is loaded from, the current
+// directory and, if there is one, the user's home directory.
+func configRoots() (config.ConfigLoadRoots, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return config.ConfigLoadRoots{}, err
+	}
+
+	roots := config.ConfigLoadRoots{CWDRoot: os.DirFS(cwd)}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		roots.Home = os.DirFS(homeDir)
+	}
+	return roots, nil
+}
+
+// loadLayeredConfig loads the effective autumn config for the project in the
+// current directory, without validating it.
+func loadLayeredConfig() (*config.LoadedConfig, error) {
+	roots, err := configRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, err := config.Load(roots, os.Environ())
+	if os.IsNotExist(err) {
+		fmt.Println("failed to load autumn config: ", err)
+		return nil, err
+	} else if err != nil {
+		fmt.Println("autumn config is malformed: ", err)
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// validateConfig loads and validates the autumn config for the project in
+// the current directory, returning all of its problems.
+func validateConfig() (*config.LoadedConfig, []config.Problem, error) {
+	loaded, err := loadLayeredConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return loaded, loaded.Validate(retrievedServiceFramework(loaded.Config)), nil
+}
+
+// retrievedServiceFramework returns the service framework to check the
+// templates to generate against. They can only be checked once it has been
+// retrieved at its configured version, e.g. a newer version might add the
+// templates, so until then it returns nil and they're skipped.
+func retrievedServiceFramework(conf config.Config) config.FrameworkSource {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	statuses, err := retriever.CheckFrameworks(cwd, conf.Service)
+	if err != nil {
+		return nil
+	}
+	for _, status := range statuses {
+		if !status.OK() {
+			return nil
+		}
+	}
+
+	source, _, err := retriever.LoadFrameworkSource(os.DirFS(cwd), conf.Service)
+	if err != nil {
+		return nil
+	}
+	return source
+}
@@ -1,13 +1,10 @@
 package main
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
 
-	"github.com/BurntSushi/toml"
 	"github.com/ttacon/autumn/lib/config"
 	"github.com/ttacon/autumn/lib/engine/retriever"
 
@@ -15,47 +12,70 @@ import (
 )
 
 func get(c *cli.Context) error {
-	var conf config.Config
-	if data, err := ioutil.ReadFile(
-		filepath.Join(
-			autumnDir,
-			configFileName,
-		),
-	); err != nil {
-		fmt.Println("failed to load autumn config: ", err)
+	if _, err := enterProjectRoot(); err != nil {
 		return err
-	} else if _, err := toml.NewDecoder(bytes.NewBuffer(data)).Decode(&conf); err != nil {
-		fmt.Println("autumn config is malformed: ", err)
+	}
+
+	conf, err := loadConfig()
+	if err != nil {
 		return err
 	}
 
-	return retrieveSourcesForEngine(conf)
+	if err := retrieveSourcesForEngine(conf); err != nil {
+		return err
+	}
+
+	// The templates to generate are only checked once the service
+	// framework is at its configured version, which it now is.
+	_, err = loadConfig()
+	return err
 }
 
-func retrieveSourcesForEngine(c config.Config) error {
-	var frameworkGetters = []config.FrameworkGetter{
+// loadConfig loads the effective autumn config for the project in the
+// current directory (see config.Load), printing every problem with it if it
+// isn't valid.
+func loadConfig() (config.Config, error) {
+	loaded, problems, err := validateConfig()
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	for _, warning := range loaded.Warnings() {
+		fmt.Fprintln(os.Stderr, "warning:", warning)
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return loaded.Config, fmt.Errorf("%w: %d problem(s)", errInvalidConfig, len(problems))
+	}
+
+	return loaded.Config, nil
+}
+
+// configuredFrameworks returns every framework of the config, configured or
+// not.
+func configuredFrameworks(c config.Config) []config.FrameworkGetter {
+	return []config.FrameworkGetter{
 		c.Controller,
 		c.Router,
 		c.Service,
 	}
+}
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
+func retrieveSourcesForEngine(c config.Config) error {
+	return retrieveFrameworks(c, configuredFrameworks(c))
+}
 
-	homeDir, err := os.UserHomeDir()
+// retrieveFrameworks retrieves the given frameworks of the config.
+func retrieveFrameworks(c config.Config, frameworkGetters []config.FrameworkGetter) error {
+	roots, err := configRoots()
 	if err != nil {
 		return err
 	}
 
-	frameworkRetriever, err := retriever.NewFrameworkRetriever(
-		c,
-		config.ConfigLoadRoots{
-			CWDRoot: os.DirFS(cwd),
-			Home:    os.DirFS(homeDir),
-		},
-	)
+	frameworkRetriever, err := retriever.NewFrameworkRetriever(c, roots)
 	if err != nil {
 		return err
 	}
@@ -68,3 +88,41 @@ func retrieveSourcesForEngine(c config.Config) error {
 
 	return nil
 }
+
+var (
+	errFrameworksNotReady = errors.New(
+		"frameworks are missing or not at their locked versions, run `autumn get` or use --get",
+	)
+)
+
+// ensureFrameworks checks that the configured frameworks have been retrieved
+// into the project in cwd at their locked versions, reporting any that
+// haven't. Unless retrieve is set this never touches the network or the
+// retrieved frameworks, if it is then the frameworks that aren't ready are
+// retrieved.
+func ensureFrameworks(cwd string, c config.Config, retrieve bool) error {
+	var notReady []config.FrameworkGetter
+	for _, framework := range configuredFrameworks(c) {
+		statuses, err := retriever.CheckFrameworks(cwd, framework)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			if status.OK() {
+				continue
+			}
+			if !retrieve {
+				fmt.Printf("framework %s: %s\n", status.Framework, status.Problem)
+			}
+			notReady = append(notReady, framework)
+		}
+	}
+
+	if len(notReady) == 0 {
+		return nil
+	} else if !retrieve {
+		return errFrameworksNotReady
+	}
+	return retrieveFrameworks(c, notReady)
+}
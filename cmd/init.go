@@ -8,15 +8,15 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/BurntSushi/toml"
 	"github.com/ttacon/autumn/lib/config"
 	cli "github.com/urfave/cli/v2"
 	"golang.org/x/mod/modfile"
 )
 
 func initCommand(c *cli.Context) error {
-	cwd, err := os.Getwd()
+	cwd, err := enterProjectRoot()
 	if err != nil {
 		fmt.Println("failed to identify current working directory: ", err)
 		return err
@@ -51,13 +51,33 @@ func initCommand(c *cli.Context) error {
 	}
 	conf.Name = packageName
 
+	// Frameworks are picked with flags or, when none are given and we're
+	// run from a terminal, by prompting for them.
+	interactive := !c.IsSet("service") && !c.IsSet("controller") && !c.IsSet("router") &&
+		isTerminal(os.Stdin)
+	prompter := newPrompter(os.Stdin, os.Stdout)
+
+	for _, kind := range frameworkKinds(&conf) {
+		var info config.FrameworkInfo
+		if interactive {
+			info, err = prompter.selectFramework(kind.name)
+		} else if value := c.String(kind.name); len(value) > 0 {
+			info, err = config.ParseFramework(value)
+		}
+		if err != nil {
+			fmt.Printf("failed to select the %s framework: %s\n", kind.name, err)
+			return err
+		}
+		*kind.info = info
+	}
+
 	configFilePath := filepath.Join(
 		autumnDir,
 		configFileName,
 	)
 
 	var buf = bytes.NewBuffer(nil)
-	if err := toml.NewEncoder(buf).Encode(conf); err != nil {
+	if err := config.Encode(buf, conf); err != nil {
 		fmt.Println("failed to prepare config for storage: ", err)
 		return err
 	} else if err := ioutil.WriteFile(configFilePath, buf.Bytes(), 0644); err != nil {
@@ -65,9 +85,43 @@ func initCommand(c *cli.Context) error {
 		return err
 	}
 
+	ignore := c.Bool("gitignore")
+	if interactive && !ignore {
+		if ignore, err = prompter.confirm(
+			fmt.Sprintf("Add %s to .gitignore?", strings.Join(ignorePatterns, " and ")),
+			true,
+		); err != nil {
+			return err
+		}
+	}
+	if ignore {
+		for _, pattern := range ignorePatterns {
+			if err := addToGitignore(cwd, pattern); err != nil {
+				fmt.Println("failed to update .gitignore: ", err)
+				return err
+			}
+		}
+	}
+
 	return retrieveSourcesForEngine(conf)
 }
 
+// frameworkKind is a kind of framework that can be picked during init.
+type frameworkKind struct {
+	name string
+	info *config.FrameworkInfo
+}
+
+// frameworkKinds returns every kind of framework, pointing into the config
+// so that the selected frameworks can be set on it.
+func frameworkKinds(conf *config.Config) []frameworkKind {
+	return []frameworkKind{
+		{"service", &conf.Service.FrameworkInfo},
+		{"controller", &conf.Controller.FrameworkInfo},
+		{"router", &conf.Router.FrameworkInfo},
+	}
+}
+
 var (
 	configFileName = "config"
 )
@@ -108,6 +162,7 @@ func pkgNameFromModFile(root fs.FS) (string, error) {
 
 var (
 	autumnDir = ".autumn"
+	cacheDir  = "cache"
 )
 
 func ensureAutumnDirExists() error {
@@ -119,3 +174,35 @@ func ensureAutumnDirExists() error {
 		os.ModeDir|0755,
 	)
 }
+
+// ignorePatterns are the .gitignore patterns for retrieved frameworks, which
+// are reproducible from the config, and the discovery cache.
+var ignorePatterns = []string{
+	"/" + autumnDir + "/frameworks/",
+	"/" + autumnDir + "/" + cacheDir + "/",
+}
+
+// addToGitignore adds the pattern to the .gitignore file in dir, creating it
+// if needed, unless it's already there.
+func addToGitignore(dir, pattern string) error {
+	gitignorePath := filepath.Join(dir, ".gitignore")
+
+	data, err := ioutil.ReadFile(gitignorePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	trimmed := strings.Trim(pattern, "/")
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.Trim(strings.TrimSpace(line), "/") == trimmed {
+			return nil
+		}
+	}
+
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, pattern+"\n"...)
+
+	return ioutil.WriteFile(gitignorePath, data, 0644)
+}
@@ -33,6 +33,21 @@ func main() {
 						"f",
 					},
 				},
+				&cli.StringFlag{
+					Name:  "service",
+					Value: "",
+				},
+				&cli.StringFlag{
+					Name:  "controller",
+					Value: "",
+				},
+				&cli.StringFlag{
+					Name:  "router",
+					Value: "",
+				},
+				&cli.BoolFlag{
+					Name: "gitignore",
+				},
 			},
 		},
 		&cli.Command{
@@ -58,8 +73,71 @@ func main() {
 						"f",
 					},
 				},
+				&cli.StringFlag{
+					Name:  "format",
+					Value: "text",
+				},
+				&cli.BoolFlag{
+					Name: "check",
+				},
+				&cli.BoolFlag{
+					Name: "get",
+				},
+				&cli.StringFlag{
+					Name:  "target",
+					Value: "",
+				},
+				&cli.StringFlag{
+					Name:  "package",
+					Value: "",
+				},
+				&cli.StringFlag{
+					Name:  "exclude",
+					Value: "",
+				},
+			},
+		},
+		&cli.Command{
+			Description: "Generate code for all models.",
+			Name:        "apply",
+			Action:      apply,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name: "dry-run",
+					Aliases: []string{
+						"n",
+					},
+				},
+				&cli.StringFlag{
+					Name:  "patch",
+					Value: "",
+					Aliases: []string{
+						"p",
+					},
+				},
+				&cli.StringFlag{
+					Name:  "plan",
+					Value: "",
+				},
+				&cli.StringFlag{
+					Name:  "target",
+					Value: "",
+				},
+				&cli.StringFlag{
+					Name:  "package",
+					Value: "",
+				},
+				&cli.StringFlag{
+					Name:  "exclude",
+					Value: "",
+				},
 			},
 		},
+		&cli.Command{
+			Description: "Manage the autumn config: `autumn config validate` or `autumn config show [--origin]`.",
+			Name:        "config",
+			Action:      configCommand,
+		},
 	}
 	if err := app.Run(os.Args); err != nil {
 		log.Fatal(err)
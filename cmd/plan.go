@@ -2,16 +2,13 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
-	"path/filepath"
 
-	"github.com/BurntSushi/toml"
-	"github.com/ttacon/autumn/lib/config"
-	"github.com/ttacon/autumn/lib/engine"
+	"github.com/ttacon/autumn/lib/generator"
 	"github.com/urfave/cli/v2"
 )
 
@@ -20,90 +17,122 @@ func plan(c *cli.Context) error {
 	//
 	//  1. Check if plan with known name exists.
 	//    a. If it does, and force flag isn't set, exit.
-	//  2. Check if we're using targeting mode.
+	//  2. Check if we're using targeting mode (--target, --package and
+	//     --exclude).
 	//  3. Load config.
-	//  4. See if all frameworks have been pulled down.
+	//  4. See if all frameworks have been pulled down at their locked
+	//     versions, retrieving them only if --get was used.
 	//  5. Generate plan (Model -> <Controller, Router, Service> -> Framework -> Templates).
 	//  6. Write file to disk.
+	//  7. Print a summary of the plan.
+	//
+	// In check mode, steps 6 and 7 are replaced by comparing the plan with
+	// the committed plan and the generated files on disk.
 
-	outputFileName := c.String("out")
-	cwd, err := os.Getwd()
+	format := c.String("format")
+	if err := generator.WriteSummary(ioutil.Discard, &generator.Summary{}, format); err != nil {
+		return err
+	}
+
+	cwd, err := enterProjectRoot()
 	if err != nil {
 		return err
 	}
 
 	root := os.DirFS(cwd)
-	if _, err := root.Open(outputFileName); err == nil && !c.Bool("force") {
+	outputFileName := userFlagPath(c, "out")
+	checkMode := c.Bool("check")
+	if _, err := os.Stat(outputFileName); err == nil && !c.Bool("force") && !checkMode {
 		return errors.New("output file already exists")
 	}
 
-	// NOTE(ttacon): punting on targeting mode for now since we support
-	// other methods for specifying generation targts.
+	conf, err := loadConfig()
+	if err != nil {
+		return err
+	}
 
-	var conf config.Config
-	if data, err := ioutil.ReadFile(
-		filepath.Join(
-			autumnDir,
-			configFileName,
-		),
-	); err != nil {
-		fmt.Println("failed to load autumn config: ", err)
+	// Planning doesn't change the project, so we only check that the
+	// frameworks are present at their locked versions unless we're asked
+	// to retrieve them. Only the discovery cache is written.
+	if err := ensureFrameworks(cwd, conf, c.Bool("get")); err != nil {
 		return err
-	} else if _, err := toml.NewDecoder(bytes.NewBuffer(data)).Decode(&conf); err != nil {
-		fmt.Println("autumn config is malformed: ", err)
+	} else if c.Bool("get") {
+		// Check the templates to generate against the frameworks that
+		// were just retrieved.
+		if conf, err = loadConfig(); err != nil {
+			return err
+		}
+	}
+
+	gener8r, err := loadGenerator(cwd, conf)
+	if err != nil {
 		return err
 	}
 
-	// NOTE(ttacon): Instead of retrieving assets, should we report missing
-	// dependencies and support retrieveing them now via a flag?
-	if err := retrieveSourcesForEngine(conf); err != nil {
+	// Identify our targets to create, narrowed down by targeting mode.
+	targets, err := identifyTargets(c, cwd, conf)
+	if err != nil {
 		return err
 	}
 
-	// Load in the engine.
-	eng, err := engine.NewEngine(root)
+	// Generate plan (Model -> <Controller, Router, Service> -> Framework -> Templates).
+	planData, err := gener8r.CreatePlan(targets)
 	if err != nil {
 		return err
 	}
 
-	// Identify our targets to create.
-	targets, err := eng.IdentifyModelTargets()
+	// In check mode we compare against the committed plan instead of
+	// writing a new one.
+	if checkMode {
+		return checkPlan(outputFileName, planData, root)
+	}
+
+	var buf = bytes.NewBuffer(nil)
+	if err := generator.WritePlan(buf, planData); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(outputFileName, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	summary, err := generator.Summarize(planData, root)
 	if err != nil {
 		return err
 	}
+	return generator.WriteSummary(os.Stdout, summary, format)
+}
 
-	// Generate plan (Model -> <Controller, Router, Service> -> Framework -> Templates).
-	var planData = make(map[string]PlanData)
-	for _, target := range targets {
-		name, err := target.Name()
-		if err != nil {
-			return err
-		}
-		planData[name] = PlanData{
-			Config: conf,
-			Model: ModelTargetPlan{
-				Name:        name,
-				PackageName: target.PkgName(),
-				Raw:         target,
-			},
-		}
+var (
+	errPlanDrifted = errors.New("plan has drifted, re-run `autumn plan` and `autumn apply`")
+)
+
+// checkPlan compares the committed plan with the current plan and the files
+// on disk, listing everything that drifted.
+func checkPlan(committedFileName string, current *generator.Plan, root fs.FS) error {
+	committedFile, err := os.Open(committedFileName)
+	if err != nil {
+		fmt.Println("failed to read committed plan: ", err)
+		return err
 	}
+	defer committedFile.Close()
 
-	rawPlanData, err := json.Marshal(planData)
+	committed, err := generator.ReadPlan(committedFile)
 	if err != nil {
+		fmt.Println("failed to load committed plan: ", err)
 		return err
 	}
 
-	return ioutil.WriteFile(outputFileName, rawPlanData, 0644)
-}
+	drifts, err := generator.DetectDrift(committed, current, root)
+	if err != nil {
+		return err
+	}
 
-type PlanData struct {
-	Model  ModelTargetPlan
-	Config config.Config
-}
+	if len(drifts) == 0 {
+		fmt.Println("plan is up to date")
+		return nil
+	}
 
-type ModelTargetPlan struct {
-	Name        string
-	PackageName string
-	Raw         interface{} // This should be versioned
+	fmt.Printf("plan has drifted from %s:\n%s\n", committedFileName, generator.FormatDrift(drifts))
+	return errPlanDrifted
 }
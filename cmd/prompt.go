@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ttacon/autumn/lib/config"
+)
+
+// isTerminal returns whether the file is a terminal, rather than e.g. a pipe
+// or a file, so that we only prompt when someone can answer.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// prompter asks questions and reads the answers, a line at a time.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// ask prints the question and returns the trimmed answer. Running out of
+// input is treated as an empty answer.
+func (p *prompter) ask(question string) (string, error) {
+	fmt.Fprint(p.out, question, " ")
+	answer, err := p.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	} else if err == io.EOF {
+		fmt.Fprintln(p.out)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// selectFramework asks for the module path of the framework of the given
+// kind. No answer means that no framework is used.
+func (p *prompter) selectFramework(kind string) (config.FrameworkInfo, error) {
+	fmt.Fprintf(p.out, "Which %s framework should be used?\n", kind)
+	fmt.Fprintln(p.out, "  Enter its module path, e.g. github.com/you/framework@v1.0.0.")
+
+	for {
+		answer, err := p.ask(fmt.Sprintf("%s framework [none]:", kind))
+		if err != nil || len(answer) == 0 {
+			return config.FrameworkInfo{}, err
+		}
+
+		info, err := config.ParseFramework(answer)
+		if err != nil {
+			fmt.Fprintln(p.out, err)
+			continue
+		}
+		return info, nil
+	}
+}
+
+// confirm asks a yes or no question, returning def when there's no answer.
+func (p *prompter) confirm(question string, def bool) (bool, error) {
+	options := "[y/N]"
+	if def {
+		options = "[Y/n]"
+	}
+
+	for {
+		answer, err := p.ask(question + " " + options)
+		if err != nil {
+			return false, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "expected y or n")
+	}
+}
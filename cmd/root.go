@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/urfave/cli/v2"
+)
+
+// invocationDir is the directory that autumn was run from, before moving to
+// the project root (see enterProjectRoot).
+var invocationDir string
+
+// enterProjectRoot changes the working directory to the root of the project
+// (see config.FindProjectRoot), so that autumn behaves the same from any of
+// the project's subdirectories, and returns it. If no root can be found we
+// stay where we are.
+func enterProjectRoot() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	if len(invocationDir) == 0 {
+		invocationDir = cwd
+	}
+
+	root, err := config.FindProjectRoot(cwd)
+	if errors.Is(err, config.ErrProjectRootNotFound) {
+		return cwd, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if root != cwd {
+		if err := os.Chdir(root); err != nil {
+			return "", err
+		}
+	}
+	return root, nil
+}
+
+// userPath resolves a path given on the command line, which is relative to
+// the directory that autumn was run from rather than the project root.
+func userPath(p string) string {
+	if len(p) == 0 || filepath.IsAbs(p) || len(invocationDir) == 0 {
+		return p
+	}
+	return filepath.Join(invocationDir, p)
+}
+
+// userFlagPath returns the path given by the named flag, resolved with
+// userPath if it was set. Defaults are relative to the project root.
+func userFlagPath(c *cli.Context, name string) string {
+	if !c.IsSet(name) {
+		return c.String(name)
+	}
+	return userPath(c.String(name))
+}
+
+// userPackagePattern converts a package pattern given on the command line,
+// e.g. `./...` run from the models directory, to one relative to the project
+// root, e.g. `./models/...`.
+func userPackagePattern(root, pattern string) string {
+	dir, suffix := pattern, ""
+	if strings.HasSuffix(pattern, "...") {
+		dir, suffix = strings.TrimSuffix(pattern, "..."), "..."
+	}
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	rel, err := filepath.Rel(root, userPath(dir))
+	if err != nil {
+		return pattern
+	}
+
+	rel = filepath.ToSlash(rel)
+	if len(suffix) > 0 {
+		if rel == "." {
+			return "./..."
+		}
+		return "./" + rel + "/..."
+	}
+	return "./" + rel
+}
+
+// isPackagePattern returns whether the selector given on the command line is
+// a package directory rather than a model name. Package directories must be
+// relative, e.g. `./models` or `../...`, as model names can contain slashes
+// too (`example.com/project/models.User`).
+func isPackagePattern(selector string) bool {
+	return selector == "." || selector == ".." ||
+		strings.HasPrefix(selector, "./") || strings.HasPrefix(selector, "../")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/ttacon/autumn/lib/engine"
+	"github.com/ttacon/autumn/lib/generator"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	errAnnotatedFilesBroken = errors.New("failed to identify models, fix the errors in the annotated files above")
+)
+
+// identifyTargets identifies the model targets of the project at rootDir,
+// narrowed down by the targeting flags (--target, --package and --exclude).
+// Package directories given to the flags are relative to where autumn was
+// run from.
+func identifyTargets(c *cli.Context, rootDir string, conf config.Config) ([]engine.ModelTarget, error) {
+	// Load in the engine, only searching the files that the config asks
+	// us to.
+	eng, err := engine.NewEngineWithOptions(os.DirFS(rootDir), engine.Options{
+		Include:   conf.Discovery.Include,
+		Exclude:   conf.Discovery.Exclude,
+		GOOS:      conf.Build.GOOS,
+		GOARCH:    conf.Build.GOARCH,
+		BuildTags: conf.Build.Tags,
+		Tests:     conf.Build.Tests,
+		CacheDir:  filepath.Join(rootDir, autumnDir, cacheDir),
+		// Problems with relationships are found while rendering the
+		// templates that use them.
+		Report: func(diagnostic engine.Diagnostic) {
+			fmt.Fprintln(os.Stderr, diagnostic)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify our targets to create.
+	targets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	// Problems with unannotated files don't change what's generated, so
+	// they're only worth a warning.
+	diagnostics := eng.Diagnostics()
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(os.Stderr, diagnostic)
+	}
+	if engine.HasErrors(diagnostics) {
+		return nil, errAnnotatedFilesBroken
+	}
+
+	// Models configured by a name that several of them share are reported
+	// whatever the selection, so that selecting doesn't change which model
+	// a config applies to.
+	if _, err := generator.ModelConfigs(conf, targets); err != nil {
+		return nil, err
+	}
+
+	var packages, excludes, excludePackages []string
+	for _, pattern := range splitFlag(c.String("package")) {
+		packages = append(packages, userPackagePattern(rootDir, pattern))
+	}
+	for _, exclude := range splitFlag(c.String("exclude")) {
+		if isPackagePattern(exclude) {
+			excludePackages = append(excludePackages, userPackagePattern(rootDir, exclude))
+		} else {
+			excludes = append(excludes, exclude)
+		}
+	}
+
+	return engine.Selector{
+		Targets:         splitFlag(c.String("target")),
+		Packages:        packages,
+		Excludes:        excludes,
+		ExcludePackages: excludePackages,
+	}.Filter(targets)
+}
+
+// splitFlag splits a comma separated flag value into its values.
+func splitFlag(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -6,6 +6,51 @@ type Config struct {
 	Controller ControllerConfig
 	Router     RouterConfig
 	Service    ServiceConfig
+
+	// Models holds per-model configuration keyed by the model's import path
+	// qualified name (e.g. `example.com/project/users.User`), or by its
+	// package qualified name or name if only one model has it.
+	Models map[string]ModelConfig
+
+	Discovery DiscoveryConfig
+	Build     BuildConfig
+
+	// Auth is never written to plans, see AuthConfig.
+	Auth AuthConfig `json:"-"`
+}
+
+// DiscoveryConfig configures which files are searched for models, on top of
+// the directories that are never searched (e.g. vendor) and those listed in
+// .autumnignore. Globs are relative to the project root and match a path
+// segment at a time, with `**` matching any number of segments, e.g.
+// `models/**` or `**/*_gen.go`.
+type DiscoveryConfig struct {
+	// Include, when set, limits discovery to the files matching at least
+	// one of its globs.
+	Include []string
+	// Exclude leaves the files and directories matching any of its globs
+	// out of discovery.
+	Exclude []string
+}
+
+// BuildConfig configures the build constraints that files must satisfy to be
+// searched for models, as with `go build`.
+type BuildConfig struct {
+	// GOOS and GOARCH default to the current platform.
+	GOOS   string
+	GOARCH string
+	// Tags are additional build tags, as with `go build -tags`.
+	Tags []string
+	// Tests searches _test.go files too.
+	Tests bool
+}
+
+// AuthConfig holds the credentials used to retrieve frameworks over HTTP(S).
+// As the project's config is usually committed, credentials belong in the
+// user's config or the environment, e.g. `AUTUMN_AUTH_TOKEN`.
+type AuthConfig struct {
+	Username string
+	Token    string `autumn:"secret"`
 }
 
 type FrameworkGetter interface {
@@ -48,6 +93,14 @@ type ServiceConfig struct {
 	TemplatesToGenerate []string
 }
 
+// ModelConfig is the configuration for a single model.
+type ModelConfig struct {
+	// Operations are the operations (e.g. Create, Retrieve, List) to
+	// generate for the model, overriding the operations implied by
+	// ServiceConfig.TemplatesToGenerate.
+	Operations []string
+}
+
 // We need to be able to specify (with sane defaults):
 //
 //  - API controller framework
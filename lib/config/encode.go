@@ -0,0 +1,139 @@
+package config
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Encode writes the config as TOML, commented so that it documents every
+// key. Unlike encoding with the toml package, frameworks that aren't
+// configured are written as commented out examples.
+//
+// Only the keys that autumn init sets, i.e. the name and frameworks, are
+// written.
+func Encode(w io.Writer, c Config) error {
+	return commentedConfig.Execute(w, c)
+}
+
+var commentedConfig = template.Must(template.New("config").Funcs(template.FuncMap{
+	"quote":         tomlString,
+	"quoteList":     tomlStrings,
+	"orExample":     orExample,
+	"commentUnless": commentUnless,
+}).Parse(`# The autumn config for {{ .Name }}.
+#
+# Check it with ` + "`autumn config validate`" + `.
+
+# Name is the module path of the project, from go.mod.
+Name = {{ quote .Name }}
+
+# Frameworks provide the templates that code is generated from. Each one is
+# retrieved, by ` + "`autumn get`" + `, from Protocol followed by Module (e.g.
+# https://github.com/you/framework) into .autumn/frameworks, and locked at
+# Version: a tag such as v1.2.3 or a commit hash. Leave Version empty to use
+# the latest commit.
+#
+# ModulePath is the directory, relative to the project root, that generated
+# code is written to.
+{{ with .Service }}
+# Services implement the operations (Create, Retrieve, ...) on each model.
+[Service]
+{{ commentUnless .Module }}Module = {{ quote (orExample .Module "github.com/you/service-framework") }}
+{{ commentUnless .Module }}Version = {{ quote .Version }}
+{{ commentUnless .Module }}Protocol = {{ quote (orExample .Protocol "https://") }}
+ModulePath = {{ quote (orExample .ModulePath "services") }}
+# TemplatesToGenerate are the service templates generated for every model,
+# when unset every operation is generated.
+{{ if .TemplatesToGenerate }}TemplatesToGenerate = {{ quoteList .TemplatesToGenerate }}{{ else }}# TemplatesToGenerate = ["CreateTemplate", "RetrieveTemplate", "ListTemplate"]{{ end }}
+{{ end }}{{ with .Controller }}
+# Controllers translate requests into calls to services.
+[Controller]
+{{ commentUnless .Module }}Module = {{ quote (orExample .Module "github.com/you/controller-framework") }}
+{{ commentUnless .Module }}Version = {{ quote .Version }}
+{{ commentUnless .Module }}Protocol = {{ quote (orExample .Protocol "https://") }}
+ModulePath = {{ quote (orExample .ModulePath "controllers") }}
+{{ end }}{{ with .Router }}
+# Routers route requests to controllers.
+[Router]
+{{ commentUnless .Module }}Module = {{ quote (orExample .Module "github.com/you/router-framework") }}
+{{ commentUnless .Module }}Version = {{ quote .Version }}
+{{ commentUnless .Module }}Protocol = {{ quote (orExample .Protocol "https://") }}
+ModulePath = {{ quote (orExample .ModulePath "routers") }}
+{{ end }}
+# Discovery limits which files are searched for models, on top of vendor,
+# testdata, node_modules and hidden directories, which never are, and the
+# paths listed in .autumnignore. Globs are relative to the project root, with
+# ** matching any number of directories.
+[Discovery]
+# Include = ["models/**"]
+# Exclude = ["**/*_gen.go", "examples/**"]
+
+# Build sets the build constraints that files must satisfy to be searched for
+# models, as with go build. GOOS and GOARCH default to the current platform.
+# _test.go files are only searched when Tests is set.
+[Build]
+# GOOS = "linux"
+# GOARCH = "amd64"
+# Tags = ["integration"]
+# Tests = false
+
+# Models can be configured individually, e.g. to only generate some of their
+# operations (which can also be set with @Autumn:Model(operations="...")):
+#
+# [Models.User]
+# Operations = ["Create", "Retrieve"]
+#
+# Models that share their name are configured by import path:
+#
+# [Models."example.com/project/users.User"]
+`))
+
+// tomlString quotes the string as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			b.WriteString(`\u`)
+			b.WriteString(leftPad(strconv.FormatInt(int64(r), 16), 4))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func leftPad(s string, n int) string {
+	return strings.Repeat("0", n-len(s)) + s
+}
+
+func tomlStrings(values []string) string {
+	var quoted []string
+	for _, value := range values {
+		quoted = append(quoted, tomlString(value))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func orExample(value, example string) string {
+	if len(value) == 0 {
+		return example
+	}
+	return value
+}
+
+// commentUnless comments out a line unless the framework is configured, so
+// that unconfigured frameworks are examples rather than incomplete.
+func commentUnless(module string) string {
+	if len(module) == 0 {
+		return "# "
+	}
+	return ""
+}
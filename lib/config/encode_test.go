@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	var tests = []Config{
+		{Name: "example.com/proj"},
+		{
+			Name: "example.com/\"proj\"",
+			Service: ServiceConfig{
+				FrameworkInfo: FrameworkInfo{
+					Module:   "github.com/ttacon/autumn-service-mongo",
+					Version:  "v1.2.3",
+					Protocol: "https://",
+				},
+				ModulePath:          "services",
+				TemplatesToGenerate: []string{"CreateTemplate", "ListTemplate"},
+			},
+			Controller: ControllerConfig{ModulePath: "controllers"},
+			Router: RouterConfig{
+				FrameworkInfo: FrameworkInfo{
+					Module:   "github.com/ttacon/autumn-router-chi",
+					Protocol: "https://",
+				},
+				ModulePath: "internal/routers",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := Encode(&buf, test); err != nil {
+			t.Error("unexpected err: ", err)
+			continue
+		}
+
+		loaded, err := loadProjectConfig(buf.Bytes())
+		if err != nil {
+			t.Errorf("failed to decode encoded config: %v\n%s", err, buf.String())
+			continue
+		} else if problems := loaded.Validate(nil); len(problems) > 0 {
+			t.Errorf("unexpected problems: %v\n%s", problems, buf.String())
+		}
+
+		// Module paths are always written, with their defaults.
+		expected := test
+		expected.Service.ModulePath = "services"
+		expected.Controller.ModulePath = "controllers"
+		if len(expected.Router.ModulePath) == 0 {
+			expected.Router.ModulePath = "routers"
+		}
+		if !reflect.DeepEqual(loaded.Config, expected) {
+			t.Errorf("expected config %+v, found %+v", expected, loaded.Config)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnknownFramework is returned when a framework isn't given by its
+	// module path.
+	ErrUnknownFramework = errors.New("unknown framework")
+)
+
+// ParseFramework returns the framework for the given value, the module path
+// of the framework optionally followed by `@` and the version to lock it at,
+// e.g. `github.com/you/framework@v0.1.0`.
+func ParseFramework(value string) (FrameworkInfo, error) {
+	module, version := value, ""
+	if i := strings.LastIndex(value, "@"); i >= 0 {
+		module, version = value[:i], value[i+1:]
+	}
+
+	// Module paths always contain a slash, e.g. github.com/you/framework.
+	if !strings.Contains(module, "/") {
+		return FrameworkInfo{}, fmt.Errorf(
+			"%w %q, expected a module path such as github.com/you/framework",
+			ErrUnknownFramework,
+			module,
+		)
+	}
+
+	return FrameworkInfo{
+		Module:   module,
+		Version:  version,
+		Protocol: "https://",
+	}, nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFramework(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected FrameworkInfo
+		err      error
+	}{
+		{
+			value: "github.com/you/router",
+			expected: FrameworkInfo{
+				Module:   "github.com/you/router",
+				Protocol: "https://",
+			},
+		},
+		{
+			value: "github.com/you/router@abc1234",
+			expected: FrameworkInfo{
+				Module:   "github.com/you/router",
+				Version:  "abc1234",
+				Protocol: "https://",
+			},
+		},
+		{
+			value: "chi@v1.0.0",
+			err:   ErrUnknownFramework,
+		},
+	}
+
+	for _, test := range tests {
+		info, err := ParseFramework(test.value)
+		if !errors.Is(err, test.err) {
+			t.Errorf("[%s] expected err %v, found %v", test.value, test.err, err)
+		} else if info != test.expected {
+			t.Errorf("[%s] expected %+v, found %+v", test.value, test.expected, info)
+		}
+	}
+}
@@ -15,15 +15,39 @@ func (fs frameworkSource) GetFramework(name string) (Framework, bool) {
 type Framework interface {
 	AddTemplate(name string, data []byte) Framework
 	GetTemplate(name string) ([]byte, bool)
+	// AddHelper adds a helper, a Go template that templates of the
+	// framework can call as a function.
+	AddHelper(name string, data []byte) Framework
+	// GetHelpers returns all helpers of the framework keyed by name.
+	GetHelpers() map[string][]byte
+	// AddPartial adds a partial, a template that is shared by all
+	// templates of the framework and can be called with
+	// `{{template "name" .}}`. Adding a partial with the name of an
+	// existing partial replaces it.
+	AddPartial(name string, data []byte) Framework
+	// GetPartials returns all partials of the framework keyed by name.
+	GetPartials() map[string][]byte
 }
 
-type framework map[string][]byte
+type framework struct {
+	templates map[string][]byte
+	helpers   map[string][]byte
+	partials  map[string][]byte
+}
 
-func (f framework) GetTemplate(name string) ([]byte, bool) {
-	data, ok := f[name]
+func (f *framework) GetTemplate(name string) ([]byte, bool) {
+	data, ok := f.templates[name]
 	return data, ok
 }
 
+func (f *framework) GetHelpers() map[string][]byte {
+	return f.helpers
+}
+
+func (f *framework) GetPartials() map[string][]byte {
+	return f.partials
+}
+
 func NewFrameworkSource() FrameworkSource {
 	return make(frameworkSource)
 }
@@ -33,13 +57,27 @@ func (fs frameworkSource) AddFramework(name string, f Framework) FrameworkSource
 	return fs
 }
 
-func (f framework) AddTemplate(name string, data []byte) Framework {
-	f[name] = data
+func (f *framework) AddTemplate(name string, data []byte) Framework {
+	f.templates[name] = data
+	return f
+}
+
+func (f *framework) AddHelper(name string, data []byte) Framework {
+	f.helpers[name] = data
+	return f
+}
+
+func (f *framework) AddPartial(name string, data []byte) Framework {
+	f.partials[name] = data
 	return f
 }
 
 func NewFramework() Framework {
-	return make(framework)
+	return &framework{
+		templates: make(map[string][]byte),
+		helpers:   make(map[string][]byte),
+		partials:  make(map[string][]byte),
+	}
 }
 
 func FrameworkSourceFromMap(data map[string]map[string][]byte) FrameworkSource {
@@ -53,3 +91,21 @@ func FrameworkSourceFromMap(data map[string]map[string][]byte) FrameworkSource {
 	}
 	return fs
 }
+
+// FrameworkManifestFileName is the name of the optional manifest file at the
+// root of a framework.
+const FrameworkManifestFileName = "autumn.toml"
+
+// FrameworkManifest describes a framework beyond its templates.
+type FrameworkManifest struct {
+	// Helpers are Go templates, keyed by name, that the templates of the
+	// framework can call as functions, e.g. with a helper of
+	//
+	//  routePath = "/{{ pluralize . | kebabCase }}"
+	//
+	// a template can use `{{ routePath .Name }}`. The helper is executed
+	// with its argument as the data (or a list of its arguments if it was
+	// called with more than one), and has access to every function that a
+	// template does, including the other helpers.
+	Helpers map[string]string
+}
@@ -0,0 +1,418 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// UserConfigPath is the path, relative to the home directory, of the
+	// user's config. It holds defaults for every project, e.g. a team's
+	// preferred frameworks and credentials.
+	UserConfigPath = ".config/autumn/config.toml"
+	// ProjectConfigPath is the path, relative to the project root, of the
+	// project's config.
+	ProjectConfigPath = ".autumn/config"
+	// EnvPrefix prefixes the environment variables that override the
+	// config, e.g. `AUTUMN_SERVICE_VERSION` overrides `Service.Version`.
+	EnvPrefix = "AUTUMN_"
+)
+
+// ignoredEnv are environment variables that have our prefix but aren't part
+// of the config.
+var ignoredEnv = map[string]bool{
+	"AUTUMN_DEBUG_LOGGING_ON": true,
+}
+
+// Origin is where an effective config value was set.
+type Origin struct {
+	// Source is the config file, or environment variable (e.g.
+	// `$AUTUMN_NAME`), that set the value. It's empty for values that
+	// weren't set anywhere.
+	Source string
+	// Line is the line of the config file that set the value, or 0.
+	Line int
+}
+
+func (o Origin) String() string {
+	switch {
+	case len(o.Source) == 0:
+		return "default"
+	case o.Line > 0:
+		return fmt.Sprintf("%s:%d", o.Source, o.Line)
+	default:
+		return o.Source
+	}
+}
+
+// LoadedConfig is the effective config of a project, merged from each of
+// its layers, along with where each value came from.
+type LoadedConfig struct {
+	Config
+
+	// Origins maps the key of every value that was set, e.g.
+	// `Service.Module` or `Models.User.Operations`, to where it was set.
+	Origins map[string]Origin
+
+	// problems are the unknown keys found while loading.
+	problems []Problem
+	// warnings are the environment variables with our prefix that aren't
+	// config keys, see Warnings.
+	warnings []Problem
+}
+
+// Load loads the config of the project at roots.CWDRoot, merging, from lowest
+// to highest precedence:
+//
+//  1. The user's config (see UserConfigPath) in roots.Home, if it exists.
+//  2. The project's config (see ProjectConfigPath), which must exist.
+//  3. Environment variables (see EnvPrefix) from environ, which is in the
+//     form returned by os.Environ. Lists are comma separated, e.g.
+//     `AUTUMN_SERVICE_TEMPLATESTOGENERATE=CreateTemplate,ListTemplate`.
+//
+// Each layer only overrides the values that it sets, so e.g. a project that
+// only sets `Service.Version` keeps the user's `Service.Module`. Lists are
+// replaced rather than appended to.
+func Load(roots ConfigLoadRoots, environ []string) (*LoadedConfig, error) {
+	l := &LoadedConfig{
+		Origins: make(map[string]Origin),
+	}
+
+	if roots.Home != nil {
+		if err := l.loadFile(roots.Home, UserConfigPath, "~/"+UserConfigPath, false); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.loadFile(roots.CWDRoot, ProjectConfigPath, ProjectConfigPath, true); err != nil {
+		return nil, err
+	}
+	l.loadEnv(environ)
+
+	return l, nil
+}
+
+// loadFile merges the values set by the TOML file at name into the config.
+func (l *LoadedConfig) loadFile(root fs.FS, name, displayName string, required bool) error {
+	data, err := fs.ReadFile(root, name)
+	if os.IsNotExist(err) && !required {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var layer Config
+	meta, err := toml.Decode(string(data), &layer)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName, err)
+	}
+
+	lines := keyLines(data)
+	for _, key := range meta.Undecoded() {
+		l.problems = append(l.problems, Problem{
+			Key:     key.String(),
+			File:    displayName,
+			Line:    lineOf(lines, key),
+			Message: "unknown config key",
+		})
+	}
+
+	for _, key := range meta.Keys() {
+		if meta.Type(key...) == "Hash" {
+			continue
+		}
+
+		canonical, ok := copyValue(
+			reflect.ValueOf(&l.Config).Elem(),
+			reflect.ValueOf(layer),
+			key,
+		)
+		if ok {
+			l.Origins[strings.Join(canonical, ".")] = Origin{
+				Source: displayName,
+				Line:   lineOf(lines, key),
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadEnv merges the values set by environment variables into the config.
+func (l *LoadedConfig) loadEnv(environ []string) {
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		name := parts[0]
+		if !strings.HasPrefix(name, EnvPrefix) || ignoredEnv[name] || len(parts) != 2 {
+			continue
+		}
+
+		key := strings.Split(strings.TrimPrefix(name, EnvPrefix), "_")
+		canonical, err := setFromEnv(reflect.ValueOf(&l.Config).Elem(), key, parts[1])
+		if errors.Is(err, errUnknownEnv) {
+			// Other tools may share our prefix (e.g. AUTUMN_HOME), so
+			// only invalid values of config keys are problems.
+			l.warnings = append(l.warnings, Problem{
+				Key:     name,
+				Message: err.Error() + ", ignoring it",
+			})
+			continue
+		} else if err != nil {
+			l.problems = append(l.problems, Problem{
+				Key:     name,
+				Message: err.Error(),
+			})
+			continue
+		}
+
+		l.Origins[strings.Join(canonical, ".")] = Origin{Source: "$" + name}
+	}
+}
+
+// Validate returns the problems with the loaded config, see Config.Validate,
+// along with any unknown keys found while loading it. Each problem is
+// reported with the file and line, or environment variable, that set it.
+func (l *LoadedConfig) Validate(source FrameworkSource) []Problem {
+	var problems []Problem
+	problems = append(problems, l.problems...)
+	for _, problem := range l.Config.Validate(source) {
+		if origin, ok := l.origin(problem.Key); ok {
+			problem.File, problem.Line = origin.Source, origin.Line
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// Warnings returns the environment variables that have our prefix but aren't
+// config keys, which are ignored. Unlike the problems returned by Validate
+// they don't make the config invalid.
+func (l *LoadedConfig) Warnings() []Problem {
+	return l.warnings
+}
+
+// origin returns where the key was set or, for keys that weren't set (e.g.
+// missing required keys), where another key of the same table was set.
+func (l *LoadedConfig) origin(key string) (Origin, bool) {
+	if origin, ok := l.Origins[key]; ok {
+		return origin, true
+	}
+
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return Origin{}, false
+	}
+
+	var siblings []string
+	for other := range l.Origins {
+		if strings.HasPrefix(other, key[:i+1]) {
+			siblings = append(siblings, other)
+		}
+	}
+	if len(siblings) == 0 {
+		return Origin{}, false
+	}
+	sort.Strings(siblings)
+	return l.Origins[siblings[0]], true
+}
+
+// Value is a single value of the effective config.
+type Value struct {
+	// Key is the dotted key of the value, e.g. `Service.Module`.
+	Key    string
+	Value  interface{}
+	Origin Origin
+	// Secret is set for values, such as tokens, that shouldn't be shown.
+	Secret bool
+}
+
+// Values returns every value of the effective config, in the order that
+// they're declared, along with where each one came from.
+func (l *LoadedConfig) Values() []Value {
+	var values []Value
+	collectValues(reflect.ValueOf(l.Config), nil, false, func(key []string, value reflect.Value, secret bool) {
+		k := strings.Join(key, ".")
+		values = append(values, Value{
+			Key:    k,
+			Value:  value.Interface(),
+			Origin: l.Origins[k],
+			Secret: secret,
+		})
+	})
+	return values
+}
+
+// collectValues calls fn for every leaf value under v.
+func collectValues(
+	v reflect.Value,
+	key []string,
+	secret bool,
+	fn func(key []string, value reflect.Value, secret bool),
+) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			fieldKey := append(append([]string{}, key...), field.Name)
+			if field.Anonymous {
+				fieldKey = key
+			}
+			collectValues(v.Field(i), fieldKey, field.Tag.Get("autumn") == "secret", fn)
+		}
+	case reflect.Map:
+		var mapKeys []string
+		for _, mapKey := range v.MapKeys() {
+			mapKeys = append(mapKeys, mapKey.String())
+		}
+		sort.Strings(mapKeys)
+		for _, mapKey := range mapKeys {
+			collectValues(
+				v.MapIndex(reflect.ValueOf(mapKey)),
+				append(append([]string{}, key...), mapKey),
+				secret,
+				fn,
+			)
+		}
+	default:
+		fn(key, v, secret)
+	}
+}
+
+// copyValue copies the value at the TOML key from src to dst, returning the
+// canonical key of the value, e.g. `Service.Module` for `service.module`.
+// It returns false if the key doesn't name a value of the config.
+func copyValue(dst, src reflect.Value, key []string) ([]string, bool) {
+	if len(key) == 0 {
+		return nil, false
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		index, name, ok := fieldByNameFold(dst.Type(), key[0])
+		if !ok {
+			return nil, false
+		}
+		if len(key) == 1 {
+			dst.FieldByIndex(index).Set(src.FieldByIndex(index))
+			return []string{name}, true
+		}
+		rest, ok := copyValue(dst.FieldByIndex(index), src.FieldByIndex(index), key[1:])
+		return append([]string{name}, rest...), ok
+
+	case reflect.Map:
+		mapKey := reflect.ValueOf(key[0])
+		srcElem := src.MapIndex(mapKey)
+		if !srcElem.IsValid() {
+			return nil, false
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		if len(key) == 1 {
+			dst.SetMapIndex(mapKey, srcElem)
+			return key[:1], true
+		}
+
+		// Map elements aren't addressable, so we merge into a copy.
+		elem := reflect.New(dst.Type().Elem()).Elem()
+		if existing := dst.MapIndex(mapKey); existing.IsValid() {
+			elem.Set(existing)
+		}
+		rest, ok := copyValue(elem, srcElem, key[1:])
+		if ok {
+			dst.SetMapIndex(mapKey, elem)
+		}
+		return append([]string{key[0]}, rest...), ok
+	}
+
+	return nil, false
+}
+
+var errUnknownEnv = errors.New("unknown autumn environment variable")
+
+// setFromEnv sets the value at the key, from an environment variable's name,
+// to the environment variable's value. Only strings, booleans and lists of
+// strings in (non-map) tables can be set.
+func setFromEnv(v reflect.Value, key []string, value string) ([]string, error) {
+	if v.Kind() != reflect.Struct || len(key) == 0 {
+		return nil, errUnknownEnv
+	}
+
+	index, name, ok := fieldByNameFold(v.Type(), key[0])
+	if !ok {
+		return nil, errUnknownEnv
+	}
+	field := v.FieldByIndex(index)
+
+	if len(key) > 1 {
+		rest, err := setFromEnv(field, key[1:], value)
+		return append([]string{name}, rest...), err
+	}
+
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				values = append(values, item)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return nil, errUnknownEnv
+	}
+	return []string{name}, nil
+}
+
+// fieldByNameFold finds the field of the struct type with the given name,
+// case insensitively as TOML decoding does, including fields promoted from
+// embedded structs.
+func fieldByNameFold(t reflect.Type, name string) ([]int, string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if index, fieldName, ok := fieldByNameFold(field.Type, name); ok {
+				return append([]int{i}, index...), fieldName, true
+			}
+			continue
+		}
+		if strings.EqualFold(field.Name, name) {
+			return []int{i}, field.Name, true
+		}
+	}
+	return nil, "", false
+}
+
+// Format returns the value as it would be written in TOML, redacting it if
+// it's a secret that has been set.
+func (v Value) Format() string {
+	switch value := v.Value.(type) {
+	case string:
+		if v.Secret && len(value) > 0 {
+			return tomlString("<redacted>")
+		}
+		return tomlString(value)
+	case []string:
+		if len(value) == 0 {
+			return "[]"
+		}
+		return tomlStrings(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
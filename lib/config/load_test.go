@@ -0,0 +1,172 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	var (
+		home = fstest.MapFS{
+			UserConfigPath: &fstest.MapFile{
+				Data: []byte(`[Service]
+Module = "example.com/svc"
+Protocol = "https://"
+
+[Auth]
+Token = "secret"
+
+[Models.User]
+Operations = ["Create"]
+`),
+			},
+		}
+		cwd = fstest.MapFS{
+			ProjectConfigPath: &fstest.MapFile{
+				Data: []byte(`Name = "example.com/proj"
+
+[service]
+version = "v1.2.3"
+
+[Router]
+Module = "example.com/router"
+Typo = true
+
+[Models.Order]
+Operations = ["List"]
+`),
+			},
+		}
+		environ = []string{
+			"HOME=/home/someone",
+			"AUTUMN_DEBUG_LOGGING_ON=true",
+			"AUTUMN_SERVICE_MODULEPATH=internal/services",
+			"AUTUMN_SERVICE_TEMPLATESTOGENERATE=CreateTemplate, ListTemplate",
+			"AUTUMN_BUILD_TESTS=true",
+			"AUTUMN_BUILD_GOOS=",
+			"AUTUMN_BOGUS=1",
+		}
+	)
+
+	loaded, err := Load(ConfigLoadRoots{CWDRoot: cwd, Home: home}, environ)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	expected := Config{
+		Name: "example.com/proj",
+		Service: ServiceConfig{
+			FrameworkInfo: FrameworkInfo{
+				Module:   "example.com/svc",
+				Version:  "v1.2.3",
+				Protocol: "https://",
+			},
+			ModulePath:          "internal/services",
+			TemplatesToGenerate: []string{"CreateTemplate", "ListTemplate"},
+		},
+		Router: RouterConfig{
+			FrameworkInfo: FrameworkInfo{Module: "example.com/router"},
+		},
+		Models: map[string]ModelConfig{
+			"User":  {Operations: []string{"Create"}},
+			"Order": {Operations: []string{"List"}},
+		},
+		Build: BuildConfig{Tests: true},
+		Auth:  AuthConfig{Token: "secret"},
+	}
+	if !reflect.DeepEqual(loaded.Config, expected) {
+		t.Errorf("expected config:\n%+v\nfound:\n%+v", expected, loaded.Config)
+	}
+
+	var (
+		userConfig      = "~/" + UserConfigPath
+		expectedOrigins = map[string]Origin{
+			"Name":                        {Source: ProjectConfigPath, Line: 1},
+			"Service.Module":              {Source: userConfig, Line: 2},
+			"Service.Protocol":            {Source: userConfig, Line: 3},
+			"Service.Version":             {Source: ProjectConfigPath, Line: 4},
+			"Service.ModulePath":          {Source: "$AUTUMN_SERVICE_MODULEPATH"},
+			"Service.TemplatesToGenerate": {Source: "$AUTUMN_SERVICE_TEMPLATESTOGENERATE"},
+			"Router.Module":               {Source: ProjectConfigPath, Line: 7},
+			"Models.User.Operations":      {Source: userConfig, Line: 9},
+			"Models.Order.Operations":     {Source: ProjectConfigPath, Line: 11},
+			"Auth.Token":                  {Source: userConfig, Line: 6},
+			"Build.Tests":                 {Source: "$AUTUMN_BUILD_TESTS"},
+			"Build.GOOS":                  {Source: "$AUTUMN_BUILD_GOOS"},
+		}
+	)
+	if !reflect.DeepEqual(loaded.Origins, expectedOrigins) {
+		t.Errorf("expected origins:\n%v\nfound:\n%v", expectedOrigins, loaded.Origins)
+	}
+
+	expectedProblems := []Problem{
+		{Key: "Router.Typo", File: ProjectConfigPath, Line: 8, Message: "unknown config key"},
+		{Key: "Router.Protocol", File: ProjectConfigPath, Line: 7, Message: `is required, e.g. "https://"`},
+	}
+	if problems := loaded.Validate(nil); !reflect.DeepEqual(problems, expectedProblems) {
+		t.Errorf("expected problems:\n%v\nfound:\n%v", expectedProblems, problems)
+	}
+
+	expectedWarnings := []Problem{
+		{Key: "AUTUMN_BOGUS", Message: "unknown autumn environment variable, ignoring it"},
+	}
+	if warnings := loaded.Warnings(); !reflect.DeepEqual(warnings, expectedWarnings) {
+		t.Errorf("expected warnings:\n%v\nfound:\n%v", expectedWarnings, warnings)
+	}
+
+	for _, value := range loaded.Values() {
+		if value.Key == "Auth.Token" && !value.Secret {
+			t.Error("expected Auth.Token to be secret")
+		} else if value.Key == "Service.Module" && value.Origin.Source != userConfig {
+			t.Errorf("unexpected origin of Service.Module: %s", value.Origin)
+		}
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	cwd := fstest.MapFS{
+		ProjectConfigPath: &fstest.MapFile{Data: []byte(`Name = "example.com/proj"`)},
+	}
+
+	loaded, err := Load(ConfigLoadRoots{CWDRoot: cwd}, []string{"AUTUMN_BUILD_TESTS=maybe"})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	expected := []Problem{{Key: "AUTUMN_BUILD_TESTS", Message: `invalid boolean "maybe"`}}
+	if problems := loaded.Validate(nil); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected problems %v, found %v", expected, problems)
+	}
+}
+
+func TestLoadUnrelatedEnv(t *testing.T) {
+	cwd := fstest.MapFS{
+		ProjectConfigPath: &fstest.MapFile{Data: []byte(`Name = "example.com/proj"`)},
+	}
+
+	loaded, err := Load(ConfigLoadRoots{CWDRoot: cwd}, []string{
+		"AUTUMN_FOO=bar",
+		"AUTUMN_HOME=/opt/autumn",
+		"AUTUMN_SERVICE=oops",
+	})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	if problems := loaded.Validate(nil); len(problems) > 0 {
+		t.Errorf("expected unrelated environment variables not to be problems, found %v", problems)
+	}
+	if warnings := loaded.Warnings(); len(warnings) != 3 {
+		t.Errorf("expected a warning for each unrelated environment variable, found %v", warnings)
+	}
+	if expected := (Config{Name: "example.com/proj"}); !reflect.DeepEqual(loaded.Config, expected) {
+		t.Errorf("expected config %+v, found %+v", expected, loaded.Config)
+	}
+}
+
+func TestLoadMissingProjectConfig(t *testing.T) {
+	if _, err := Load(ConfigLoadRoots{CWDRoot: fstest.MapFS{}}, nil); err == nil {
+		t.Error("expected a missing project config to fail")
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// ErrProjectRootNotFound is returned when neither dir nor any of its
+	// parents look like the root of a project.
+	ErrProjectRootNotFound = errors.New("no .autumn/config, go.work or go.mod found in any parent directory")
+)
+
+// FindProjectRoot returns the root of the project that dir is in, so that
+// autumn can be run from any of its subdirectories. The root is the nearest
+// directory, starting at dir and walking up, that contains (in order of
+// preference):
+//
+//  1. The project's config (see ProjectConfigPath).
+//  2. A go.work file, for a workspace of modules.
+//  3. A go.mod file.
+//
+// so e.g. a workspace's config is found from within any of its modules.
+func FindProjectRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, marker := range []string{
+		filepath.FromSlash(ProjectConfigPath),
+		"go.work",
+		"go.mod",
+	} {
+		if root, ok := findParentWith(dir, marker); ok {
+			return root, nil
+		}
+	}
+	return "", ErrProjectRootNotFound
+}
+
+// findParentWith returns the nearest of dir and its parents that contains
+// the named file.
+func findParentWith(dir, name string) (string, bool) {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return dir, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
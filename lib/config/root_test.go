@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProjectRoot(t *testing.T) {
+	tmp := t.TempDir()
+	for name, data := range map[string]string{
+		"project/go.mod":                   "module example.com/project\n",
+		"project/.autumn/config":           "Name = \"example.com/project\"\n",
+		"project/models/user.go":           "package models\n",
+		"workspace/go.work":                "go 1.18\n",
+		"workspace/users/go.mod":           "module example.com/users\n",
+		"workspace/users/models/user.go":   "package models\n",
+		"workspace/shared/shared.go":       "package shared\n",
+		"configured/go.work":               "go 1.18\n",
+		"configured/.autumn/config":        "Name = \"example.com/configured\"\n",
+		"configured/users/go.mod":          "module example.com/users\n",
+		"configured/users/models/user.go":  "package models\n",
+		"module/go.mod":                    "module example.com/module\n",
+		"module/internal/models/models.go": "package models\n",
+	} {
+		path := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tests = []struct {
+		dir      string
+		expected string
+	}{
+		{"project", "project"},
+		{"project/models", "project"},
+		{"workspace", "workspace"},
+		{"workspace/shared", "workspace"},
+		{"workspace/users/models", "workspace"},
+		{"configured/users/models", "configured"},
+		{"module/internal/models", "module"},
+	}
+
+	for _, test := range tests {
+		root, err := FindProjectRoot(filepath.Join(tmp, test.dir))
+		if err != nil {
+			t.Error("unexpected err: ", err)
+		} else if expected := filepath.Join(tmp, test.expected); root != expected {
+			t.Errorf("[%s] expected root %q, found %q", test.dir, expected, root)
+		}
+	}
+}
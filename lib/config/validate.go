@@ -0,0 +1,315 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ttacon/autumn/lib/engine"
+	"golang.org/x/mod/semver"
+)
+
+// KnownProtocols are the protocols that frameworks can be retrieved with.
+var KnownProtocols = []string{
+	"https://",
+	"http://",
+	"ssh://",
+	"git://",
+	"file://",
+}
+
+// Problem is a single problem found when validating a config.
+type Problem struct {
+	// Key is the config key with the problem, e.g. `Service.Version`.
+	Key string
+	// File is the config file that the problem is in, if known.
+	File string
+	// Line is the line of the config file that the problem is on, or 0 if
+	// the key isn't in the file (e.g. a missing required key).
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	switch {
+	case len(p.File) > 0 && p.Line > 0:
+		return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Key, p.Message)
+	case len(p.File) > 0:
+		return fmt.Sprintf("%s: %s: %s", p.File, p.Key, p.Message)
+	case p.Line > 0:
+		return fmt.Sprintf("line %d: %s: %s", p.Line, p.Key, p.Message)
+	default:
+		return fmt.Sprintf("%s: %s", p.Key, p.Message)
+	}
+}
+
+// ValidationError is returned when a config has problems, it lists all of
+// them rather than just the first.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var lines = []string{"invalid autumn config:"}
+	for _, problem := range e.Problems {
+		lines = append(lines, "  "+problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the config for problems:
+//
+//   - Required keys that are missing.
+//   - Unknown framework protocols.
+//   - Framework versions that are neither a `v` prefixed semantic version
+//     tag nor a commit hash.
+//   - Module paths that are outside of the project.
+//   - Malformed discovery globs.
+//   - TemplatesToGenerate that don't exist in the service framework, if
+//     source is given and the framework has been loaded into it.
+//
+// As the config has already been decoded none of the problems have a line,
+// see LoadedConfig.Validate.
+func (c Config) Validate(source FrameworkSource) []Problem {
+	v := &validator{}
+
+	if len(c.Name) == 0 {
+		v.report("Name", "is required")
+	}
+
+	frameworks := []struct {
+		section    string
+		info       FrameworkInfo
+		modulePath string
+	}{
+		{"Controller", c.Controller.FrameworkInfo, c.Controller.ModulePath},
+		{"Router", c.Router.FrameworkInfo, c.Router.ModulePath},
+		{"Service", c.Service.FrameworkInfo, c.Service.ModulePath},
+	}
+	for _, framework := range frameworks {
+		v.validateFramework(framework.section, framework.info)
+		v.validateModulePath(framework.section+".ModulePath", framework.modulePath)
+	}
+
+	v.validateGlobs("Discovery.Include", c.Discovery.Include)
+	v.validateGlobs("Discovery.Exclude", c.Discovery.Exclude)
+
+	if len(c.Service.TemplatesToGenerate) > 0 && source != nil {
+		if framework, ok := source.GetFramework(c.Service.Module); ok {
+			for _, name := range c.Service.TemplatesToGenerate {
+				if _, ok := framework.GetTemplate(name); !ok {
+					v.report(
+						"Service.TemplatesToGenerate",
+						fmt.Sprintf("template %q does not exist in %s", name, c.Service.Module),
+					)
+				}
+			}
+		}
+	}
+
+	return v.problems
+}
+
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) report(key, message string) {
+	v.problems = append(v.problems, Problem{
+		Key:     key,
+		Message: message,
+	})
+}
+
+// lineOf returns the line of the key, given as its parts, falling back to
+// the line of the closest table that contains it.
+func lineOf(lines map[string]int, key []string) int {
+	for i := len(key); i > 0; i-- {
+		if line, ok := lines[joinKey(key[:i])]; ok {
+			return line
+		}
+	}
+	return 0
+}
+
+func (v *validator) validateFramework(section string, info FrameworkInfo) {
+	if len(info.Module) == 0 {
+		if len(info.Version) > 0 || len(info.Protocol) > 0 {
+			v.report(section+".Module", "is required when a version or protocol is set")
+		}
+		return
+	}
+
+	if len(info.Protocol) == 0 {
+		v.report(section+".Protocol", "is required, e.g. \"https://\"")
+	} else if !isKnownProtocol(info.Protocol) {
+		v.report(
+			section+".Protocol",
+			fmt.Sprintf("unknown protocol %q, expected one of %s", info.Protocol, strings.Join(KnownProtocols, ", ")),
+		)
+	}
+
+	if len(info.Version) > 0 && !isValidVersion(info.Version) {
+		v.report(
+			section+".Version",
+			fmt.Sprintf("invalid version %q, expected a tag such as v1.2.3 or a commit hash", info.Version),
+		)
+	}
+}
+
+func (v *validator) validateModulePath(key, modulePath string) {
+	if len(modulePath) == 0 {
+		return
+	}
+
+	slashed := filepath.ToSlash(modulePath)
+	if path.IsAbs(slashed) || filepath.IsAbs(modulePath) {
+		v.report(key, "must be relative to the project root")
+	} else if cleaned := path.Clean(slashed); cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		v.report(key, "must be inside the project")
+	}
+}
+
+func (v *validator) validateGlobs(key string, globs []string) {
+	for _, glob := range globs {
+		if err := engine.ValidateGlob(glob); err != nil {
+			v.report(key, err.Error())
+		}
+	}
+}
+
+func isKnownProtocol(protocol string) bool {
+	for _, known := range KnownProtocols {
+		if protocol == known {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidVersion(version string) bool {
+	if strings.HasPrefix(version, "v") {
+		// Require a complete version, e.g. v1.2.3 rather than v1, ignoring
+		// any build metadata.
+		return semver.IsValid(version) &&
+			semver.Canonical(version) == strings.SplitN(version, "+", 2)[0]
+	}
+
+	if len(version) < 7 || len(version) > 40 {
+		return false
+	}
+	for _, r := range strings.ToLower(version) {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// keyLines maps the lower cased path of every key and table in the TOML data,
+// as joined by joinKey, to the line that it is defined on. TOML keys match our
+// config case insensitively, hence the lower casing.
+func keyLines(data []byte) map[string]int {
+	var (
+		lines     = make(map[string]int)
+		table     []string
+		multiline string
+		scanner   = bufio.NewScanner(bytes.NewReader(data))
+	)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip the contents of multi-line strings.
+		if len(multiline) > 0 {
+			if strings.Contains(line, multiline) {
+				multiline = ""
+			}
+			continue
+		}
+
+		switch {
+		case len(line) == 0 || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "["):
+			table, _ = splitKey(strings.TrimLeft(line, "["))
+			key := joinKey(table)
+			if _, ok := lines[key]; !ok {
+				lines[key] = lineNum
+			}
+			continue
+		}
+
+		parts, end := splitKey(line)
+		if end >= len(line) || line[end] != '=' {
+			continue
+		}
+
+		key := joinKey(append(append([]string{}, table...), parts...))
+		if _, ok := lines[key]; !ok {
+			lines[key] = lineNum
+		}
+
+		value := line[end+1:]
+		for _, quote := range []string{`"""`, `'''`} {
+			if strings.Count(value, quote) == 1 {
+				multiline = quote
+			}
+		}
+	}
+
+	return lines
+}
+
+// splitKey splits the dotted TOML key at the start of the line into its lower
+// cased parts, removing the quotes of quoted parts, e.g.
+// `Models."example.com/users.User"` is split into "models" and
+// "example.com/users.user". The key ends at the first `=`, `]` or `#` outside
+// of quotes, whose offset is returned along with the parts.
+func splitKey(line string) ([]string, int) {
+	var (
+		parts []string
+		part  strings.Builder
+		quote byte
+		i     int
+	)
+
+	for ; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == '"' && c == '\\' && i+1 < len(line):
+			i++
+			part.WriteByte(line[i])
+		case quote != 0:
+			part.WriteByte(c)
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '.':
+			parts = append(parts, strings.ToLower(part.String()))
+			part.Reset()
+		case c == '=' || c == ']' || c == '#':
+			return append(parts, strings.ToLower(part.String())), i
+		case c != ' ' && c != '\t':
+			part.WriteByte(c)
+		}
+	}
+
+	return append(parts, strings.ToLower(part.String())), i
+}
+
+// joinKey joins the parts of a key, as split by splitKey, into a key of
+// keyLines. Parts are quoted so that a part containing a `.` doesn't clash
+// with a key of several parts.
+func joinKey(parts []string) string {
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = strconv.Quote(strings.ToLower(part))
+	}
+	return strings.Join(quoted, ".")
+}
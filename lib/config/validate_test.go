@@ -0,0 +1,165 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// loadProjectConfig loads the data as the only layer of a project's config.
+func loadProjectConfig(data []byte) (*LoadedConfig, error) {
+	return Load(ConfigLoadRoots{
+		CWDRoot: fstest.MapFS{
+			ProjectConfigPath: &fstest.MapFile{Data: data},
+		},
+	}, nil)
+}
+
+func TestValidate(t *testing.T) {
+	var source = FrameworkSourceFromMap(map[string]map[string][]byte{
+		"example.com/svc": {
+			"CreateTemplate": []byte(`create`),
+		},
+	})
+
+	var tests = []struct {
+		name     string
+		data     string
+		expected []Problem
+	}{
+		{
+			name: "valid",
+			data: `Name = "example.com/proj"
+
+[Service]
+Module = "example.com/svc"
+Version = "v1.2.3"
+Protocol = "https://"
+ModulePath = "internal/services"
+TemplatesToGenerate = ["CreateTemplate"]
+
+[Router]
+Module = "example.com/router"
+Version = "0123abcd"
+Protocol = "ssh://"
+`,
+		},
+		{
+			name: "missing required keys",
+			data: `[Service]
+Version = "v1.2.3"
+
+[Router]
+Module = "example.com/router"
+`,
+			expected: []Problem{
+				{Key: "Name", Message: "is required"},
+				{Key: "Router.Protocol", Line: 5, Message: `is required, e.g. "https://"`},
+				{Key: "Service.Module", Line: 2, Message: "is required when a version or protocol is set"},
+			},
+		},
+		{
+			name: "invalid values",
+			data: `Name = "example.com/proj"
+
+[Service]
+Module = "example.com/svc"
+Version = "1.2.3"
+Protocol = "ftp://"
+ModulePath = "../services"
+TemplatesToGenerate = ["CreateTemplate", "ReadTemplate"]
+
+[controller]
+module = "example.com/ctrl"
+protocol = "https://"
+version = "v1"
+modulePath = "/abs"
+`,
+			expected: []Problem{
+				{Key: "Controller.Version", Line: 13, Message: `invalid version "v1", expected a tag such as v1.2.3 or a commit hash`},
+				{Key: "Controller.ModulePath", Line: 14, Message: "must be relative to the project root"},
+				{Key: "Service.Protocol", Line: 6, Message: `unknown protocol "ftp://", expected one of https://, http://, ssh://, git://, file://`},
+				{Key: "Service.Version", Line: 5, Message: `invalid version "1.2.3", expected a tag such as v1.2.3 or a commit hash`},
+				{Key: "Service.ModulePath", Line: 7, Message: "must be inside the project"},
+				{Key: "Service.TemplatesToGenerate", Line: 8, Message: `template "ReadTemplate" does not exist in example.com/svc`},
+			},
+		},
+		{
+			name: "invalid globs",
+			data: `Name = "example.com/proj"
+
+[Discovery]
+Include = ["models/**", "/abs/**"]
+Exclude = ["[a-"]
+`,
+			expected: []Problem{
+				{Key: "Discovery.Include", Line: 4, Message: `bad discovery pattern "/abs/**": must be a relative path`},
+				{Key: "Discovery.Exclude", Line: 5, Message: `bad discovery pattern "[a-": syntax error in pattern`},
+			},
+		},
+		{
+			name: "unknown keys",
+			data: `Name = "example.com/proj"
+Nmae = "typo"
+
+[Service]
+Modul = "example.com/svc"
+TemplatesToGenerate = [
+  "CreateTemplate",
+]
+
+[Models.User]
+Operations = ["Create"]
+Extra = true
+
+[Unknown]
+Key = 1
+`,
+			expected: []Problem{
+				{Key: "Nmae", Line: 2, Message: "unknown config key"},
+				{Key: "Service.Modul", Line: 5, Message: "unknown config key"},
+				{Key: "Models.User.Extra", Line: 12, Message: "unknown config key"},
+				{Key: "Unknown", Line: 14, Message: "unknown config key"},
+				{Key: "Unknown.Key", Line: 15, Message: "unknown config key"},
+			},
+		},
+		{
+			name: "unknown keys of quoted tables",
+			data: `Name = "example.com/proj"
+Models."example.com/proj/users.User".Extra = true
+
+[ Models . "example.com/proj/orders.Order" ] # orders
+Operations = ["Create"]
+"Extra" = true
+`,
+			expected: []Problem{
+				{Key: "Models.example.com/proj/users.User.Extra", Line: 2, Message: "unknown config key"},
+				{Key: "Models.example.com/proj/orders.Order.Extra", Line: 6, Message: "unknown config key"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		loaded, err := loadProjectConfig([]byte(test.data))
+		if err != nil {
+			t.Error("unexpected err: ", err)
+			continue
+		}
+
+		// Every problem that's on a line is in the project's config.
+		for i := range test.expected {
+			if test.expected[i].Line > 0 {
+				test.expected[i].File = ProjectConfigPath
+			}
+		}
+		if problems := loaded.Validate(source); !reflect.DeepEqual(problems, test.expected) {
+			t.Errorf("[%s] expected problems:\n%v\nfound:\n%v", test.name, test.expected, problems)
+		}
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	if _, err := loadProjectConfig([]byte(`Name = `)); err == nil {
+		t.Error("expected malformed config to fail")
+	}
+}
@@ -0,0 +1,245 @@
+// Package diff produces unified diffs between two versions of a file.
+//
+// The diffs produced here are intended for generated source files, which
+// tend to be small, so we use a straightforward longest common subsequence
+// over lines rather than anything more clever.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines to show around each change,
+// matching the default of `diff -u` and `git diff`.
+const contextLines = 3
+
+// DevNull is the name to use for a side of the diff that does not exist,
+// i.e. when a file is being created or deleted.
+const DevNull = "/dev/null"
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	// line is the index into the old lines for equal and delete
+	// operations and the index into the new lines for insert operations.
+	line int
+}
+
+// Unified returns a unified diff that transforms oldContent (named oldName)
+// into newContent (named newName). It returns nil if the two contents are
+// identical.
+func Unified(oldName, newName string, oldContent, newContent []byte) []byte {
+	if bytes.Equal(oldContent, newContent) {
+		return nil
+	}
+
+	oldLines, newLines := splitLines(oldContent), splitLines(newContent)
+	ops := editScript(oldLines, newLines)
+
+	var buf = bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", oldName, newName)
+
+	for _, h := range hunks(ops) {
+		writeHunk(buf, h, ops, oldLines, newLines)
+	}
+
+	return buf.Bytes()
+}
+
+// splitLines splits content into lines, keeping the trailing newline on each
+// line so that a missing newline at the end of the file shows up in the diff.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript computes the operations needed to turn a into b.
+func editScript(a, b []string) []op {
+	// Trim the common prefix and suffix, this keeps the table small for
+	// the common case of a few changed lines in a larger file.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// midA[i:] and midB[j:].
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []op
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, op{kind: opEqual, line: i})
+	}
+
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			ops = append(ops, op{kind: opEqual, line: prefix + i})
+			i++
+			j++
+		case j == len(midB) || (i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{kind: opDelete, line: prefix + i})
+			i++
+		default:
+			ops = append(ops, op{kind: opInsert, line: prefix + j})
+			j++
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		ops = append(ops, op{kind: opEqual, line: len(a) - suffix + k})
+	}
+
+	return ops
+}
+
+// hunk is a range [start, end) of the edit script.
+type hunk struct {
+	start, end int
+}
+
+// hunks groups the edit script into hunks, each surrounded by up to
+// contextLines of unchanged lines. Changes that are close enough together to
+// share context are merged into a single hunk.
+func hunks(ops []op) []hunk {
+	var result []hunk
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == opEqual {
+			continue
+		}
+
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+
+		// Find the end of this run of changes, absorbing any following
+		// runs that are within two context windows of it.
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == opEqual {
+				next++
+			}
+			if next == len(ops) || next-end > 2*contextLines {
+				break
+			}
+			end = next
+		}
+
+		stop := end + contextLines
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		if len(result) > 0 && result[len(result)-1].end >= start {
+			result[len(result)-1].end = stop
+		} else {
+			result = append(result, hunk{start: start, end: stop})
+		}
+		i = end
+	}
+	return result
+}
+
+func writeHunk(buf *bytes.Buffer, h hunk, ops []op, oldLines, newLines []string) {
+	// Work out where the hunk starts in each file. Every operation before
+	// the hunk advances one or both of the files.
+	oldStart, newStart := 0, 0
+	for _, o := range ops[:h.start] {
+		switch o.kind {
+		case opEqual:
+			oldStart++
+			newStart++
+		case opDelete:
+			oldStart++
+		case opInsert:
+			newStart++
+		}
+	}
+
+	var oldCount, newCount int
+	var body = bytes.NewBuffer(nil)
+	for _, o := range ops[h.start:h.end] {
+		var prefix, line string
+		switch o.kind {
+		case opEqual:
+			prefix, line = " ", oldLines[o.line]
+			oldCount++
+			newCount++
+		case opDelete:
+			prefix, line = "-", oldLines[o.line]
+			oldCount++
+		case opInsert:
+			prefix, line = "+", newLines[o.line]
+			newCount++
+		}
+		body.WriteString(prefix)
+		body.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			body.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+
+	fmt.Fprintf(
+		buf,
+		"@@ -%s +%s @@\n",
+		hunkRange(oldStart, oldCount),
+		hunkRange(newStart, newCount),
+	)
+	buf.Write(body.Bytes())
+}
+
+// hunkRange formats the range of a hunk in one of the files. Lines are one
+// indexed, and an empty range refers to the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// knownAnnotations are the annotations that autumn understands.
+var knownAnnotations = []string{
+	autumnModelIdentifier,
+	autumnFieldIdentifier,
+}
+
+// fieldAnnotationArgs are the arguments that `@Autumn:Field` takes.
+var fieldAnnotationArgs = []string{"ref"}
+
+// checkFieldAnnotation returns diagnostics for an `@Autumn:Field` annotation,
+// found at the start of text, that isn't on a struct field or has arguments
+// that it doesn't take.
+func checkFieldAnnotation(node ast.Node, text string, pos token.Position) []Diagnostic {
+	if _, ok := node.(*ast.Field); !ok {
+		return []Diagnostic{newDiagnostic(
+			SeverityWarning,
+			pos,
+			fmt.Sprintf("%s is only supported on struct fields", autumnFieldIdentifier),
+		)}
+	}
+
+	args, err := parseAnnotationArgs(text, autumnFieldIdentifier)
+	if err != nil {
+		return []Diagnostic{newDiagnostic(SeverityError, pos, err.Error())}
+	}
+
+	var diagnostics []Diagnostic
+	for key := range args {
+		known := false
+		for _, arg := range fieldAnnotationArgs {
+			known = known || key == arg
+		}
+		if !known {
+			diagnostics = append(diagnostics, newDiagnostic(
+				SeverityWarning,
+				pos,
+				fmt.Sprintf("unknown %s argument %q, expected one of: %s", autumnFieldIdentifier, key, strings.Join(fieldAnnotationArgs, ", ")),
+			))
+		}
+	}
+	return diagnostics
+}
+
+// fieldAnnotation returns the arguments of the `@Autumn:Field` annotation of
+// the struct field, if it has one.
+func fieldAnnotation(field *ast.Field) map[string]string {
+	for _, group := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, comment := range group.List {
+			if idx := strings.Index(comment.Text, autumnFieldIdentifier); idx >= 0 {
+				// Malformed arguments are reported by discovery.
+				args, _ := parseAnnotationArgs(comment.Text[idx:], autumnFieldIdentifier)
+				return args
+			}
+		}
+	}
+	return nil
+}
+
+// annotationPattern matches anything that looks like an annotation, e.g.
+// `@Autumn:Model` or `@autumn:modle`.
+var annotationPattern = regexp.MustCompile(`@[A-Za-z]+(:[A-Za-z]*)?`)
+
+// findAnnotations returns the annotations in the comment text, along with
+// their offsets in the text.
+func findAnnotations(text string) ([]string, []int) {
+	var (
+		annotations []string
+		offsets     []int
+	)
+	for _, loc := range annotationPattern.FindAllStringIndex(text, -1) {
+		annotations = append(annotations, text[loc[0]:loc[1]])
+		offsets = append(offsets, loc[0])
+	}
+	return annotations, offsets
+}
+
+// suggestAnnotation returns the known annotation that the given annotation
+// is most likely a typo of, and whether it looks like one at all. Known
+// annotations, and annotations nowhere near a known one (e.g. `@param`), are
+// not typos.
+func suggestAnnotation(annotation string) (string, bool) {
+	lowered := strings.ToLower(annotation)
+	for _, known := range knownAnnotations {
+		if annotation == known {
+			return "", false
+		}
+	}
+
+	for _, known := range knownAnnotations {
+		if levenshtein(lowered, strings.ToLower(known)) <= 2 {
+			return known, true
+		}
+	}
+
+	// Anything else in our namespace is unknown, but we can't tell what
+	// was meant.
+	if strings.HasPrefix(lowered, "@autumn") {
+		return "", true
+	}
+	return "", false
+}
+
+// describeMisplaced describes the node that an annotation was placed on, and
+// what to do instead, for when it isn't a struct type. Specs are described
+// using the token of the declaration that they belong to, from decls.
+func describeMisplaced(node ast.Node, decls map[ast.Spec]token.Token) string {
+	switch n := node.(type) {
+	case *ast.TypeSpec:
+		if _, ok := n.Type.(*ast.InterfaceType); ok {
+			return fmt.Sprintf("interface `%s`, only struct types are supported", n.Name)
+		} else if n.Assign.IsValid() {
+			return fmt.Sprintf("type alias `%s = %s`, only struct types are supported", n.Name, types.ExprString(n.Type))
+		}
+		return fmt.Sprintf("type `%s %s`, only struct types are supported", n.Name, types.ExprString(n.Type))
+	case *ast.ValueSpec:
+		return fmt.Sprintf("%s `%s`, only struct types are supported", decls[n], n.Names[0])
+	case *ast.GenDecl:
+		if n.Lparen.IsValid() || len(n.Specs) != 1 {
+			return fmt.Sprintf("%s block, only struct types are supported", n.Tok)
+		}
+		return describeMisplaced(n.Specs[0], map[ast.Spec]token.Token{n.Specs[0]: n.Tok})
+	case *ast.FuncDecl:
+		return fmt.Sprintf("func `%s`, annotate a struct type instead", n.Name)
+	case *ast.Field:
+		if len(n.Names) > 0 {
+			return fmt.Sprintf("field `%s`, annotate the struct type instead", n.Names[0])
+		}
+		return fmt.Sprintf("embedded field `%s`, annotate the struct type instead", types.ExprString(n.Type))
+	case *ast.File:
+		return "package clause, annotate a struct type instead"
+	default:
+		return "statement, annotate a struct type instead"
+	}
+}
+
+// annotationPosition returns the position of the annotation at the given
+// offset within the comment.
+func annotationPosition(fset *token.FileSet, comment *ast.Comment, offset int) token.Position {
+	return fset.Position(comment.Pos() + token.Pos(offset))
+}
+
+// parseAnnotationArgs parses the optional arguments that follow an annotation
+// in the given comment text, e.g.
+//
+//	@Autumn:Model(operations="Retrieve,List", readOnly=true)
+//
+// Arguments are comma separated `key=value` pairs, values can be quoted Go
+// strings if they contain commas or parentheses. An annotation without
+// arguments has no arguments.
+func parseAnnotationArgs(text, annotation string) (map[string]string, error) {
+	var args = make(map[string]string)
+
+	idx := strings.Index(text, annotation)
+	if idx < 0 {
+		return args, nil
+	}
+	rest := text[idx+len(annotation):]
+	if !strings.HasPrefix(rest, "(") {
+		return args, nil
+	}
+	rest = rest[1:]
+
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if strings.HasPrefix(rest, ")") {
+			return args, nil
+		}
+
+		eq := strings.IndexAny(rest, "=,)")
+		if eq < 0 || rest[eq] != '=' {
+			return nil, fmt.Errorf("malformed %s arguments: expected key=value", annotation)
+		}
+		key := strings.TrimSpace(rest[:eq])
+		if len(key) == 0 {
+			return nil, fmt.Errorf("malformed %s arguments: missing key", annotation)
+		}
+		rest = strings.TrimLeftFunc(rest[eq+1:], unicode.IsSpace)
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("malformed %s arguments: %v", annotation, err)
+			}
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return nil, fmt.Errorf("malformed %s arguments: %v", annotation, err)
+			}
+			rest = rest[len(quoted):]
+		} else {
+			end := strings.IndexAny(rest, ",)")
+			if end < 0 {
+				return nil, fmt.Errorf("malformed %s arguments: missing )", annotation)
+			}
+			value = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+		args[key] = value
+
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(rest, ","):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, ")"):
+			return args, nil
+		default:
+			return nil, fmt.Errorf("malformed %s arguments: missing )", annotation)
+		}
+	}
+}
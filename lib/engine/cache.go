@@ -0,0 +1,243 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/token"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// CacheFileName is the name of the file, within Options.CacheDir, that
+// discovery results are cached in.
+const CacheFileName = "discovery.json"
+
+// cacheVersion is the version of the cache format, and of how files are
+// discovered. It must be bumped whenever either changes, caches from other
+// versions are ignored.
+const cacheVersion = 4
+
+// discoveryCache caches what was discovered in each file, keyed by the file's
+// path and the hash of its content, along with the fields of its models, so
+// that unchanged files don't need to be parsed again.
+type discoveryCache struct {
+	Version int `json:"version"`
+	// Modules are the modules that the fields of models were resolved
+	// with, as they decide which packages imports refer to.
+	Modules []Module              `json:"modules"`
+	Files   map[string]cachedFile `json:"files"`
+}
+
+// cachedFile is what was discovered in a single file.
+type cachedFile struct {
+	Hash        string        `json:"hash"`
+	Models      []cachedModel `json:"models"`
+	Diagnostics []Diagnostic  `json:"diagnostics"`
+}
+
+// cachedModel is a model target without its syntax tree, which is parsed
+// again only if it's needed.
+type cachedModel struct {
+	Name           string            `json:"name"`
+	TypeName       string            `json:"typeName"`
+	TypeParams     []TypeParam       `json:"typeParams"`
+	TypeArgs       []string          `json:"typeArgs"`
+	Package        string            `json:"package"`
+	Doc            string            `json:"doc"`
+	Offset         int               `json:"offset"`
+	Line           int               `json:"line"`
+	Column         int               `json:"column"`
+	AnnotationArgs map[string]string `json:"annotationArgs"`
+
+	// Fields are the model's fields, which are only valid while the
+	// packages that they were resolved from, hashed by directory in
+	// Packages, are unchanged.
+	Fields   []Field           `json:"fields"`
+	Packages map[string]string `json:"packages"`
+	// Imports are the directories of the packages that the fields refer
+	// to, see modelTarget.importedDirs.
+	Imports map[string]string `json:"imports"`
+}
+
+// contentHashOf returns the hash that the cache records for file contents.
+func contentHashOf(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// readCache reads the cache in dir. A missing, unreadable or outdated cache is
+// treated as empty, as everything in it can be rediscovered.
+func readCache(dir string) *discoveryCache {
+	var cache discoveryCache
+	data, err := ioutil.ReadFile(filepath.Join(dir, CacheFileName))
+	if err != nil || json.Unmarshal(data, &cache) != nil || cache.Version != cacheVersion {
+		cache = discoveryCache{}
+	}
+	if cache.Files == nil {
+		cache.Files = make(map[string]cachedFile)
+	}
+	cache.Version = cacheVersion
+	return &cache
+}
+
+// write writes the cache to dir, unless it's unchanged from what's there.
+func (c *discoveryCache) write(dir string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	cachePath := filepath.Join(dir, CacheFileName)
+	if existing, err := ioutil.ReadFile(cachePath); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, os.ModeDir|0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that concurrent runs never see
+	// a partially written cache.
+	tmp, err := ioutil.TempFile(dir, CacheFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath)
+}
+
+// cacheFile returns the cache entry for the model targets and diagnostics
+// discovered in a file.
+func cacheFile(hash string, targets []ModelTarget, diagnostics []Diagnostic) cachedFile {
+	entry := cachedFile{
+		Hash:        hash,
+		Diagnostics: diagnostics,
+	}
+	for _, target := range targets {
+		mt := target.(*modelTarget)
+		entry.Models = append(entry.Models, cachedModel{
+			Name:           mt.name,
+			TypeName:       mt.typeName,
+			TypeParams:     mt.typeParams,
+			TypeArgs:       mt.typeArgs,
+			Package:        mt.pkgName,
+			Doc:            mt.docText,
+			Offset:         mt.definitionPosition.Offset,
+			Line:           mt.definitionPosition.Line,
+			Column:         mt.definitionPosition.Column,
+			AnnotationArgs: mt.annotationArgs,
+		})
+	}
+	return entry
+}
+
+// targets returns the model targets of a cached file. Their syntax trees are
+// parsed from root when first needed.
+func (f cachedFile) targets(root fs.FS, fileName string) []ModelTarget {
+	var targets []ModelTarget
+	for _, model := range f.Models {
+		targets = append(targets, &modelTarget{
+			name:       model.Name,
+			typeName:   model.TypeName,
+			typeParams: model.TypeParams,
+			typeArgs:   model.TypeArgs,
+			docText:    model.Doc,
+			definitionPosition: token.Position{
+				Filename: fileName,
+				Offset:   model.Offset,
+				Line:     model.Line,
+				Column:   model.Column,
+			},
+			pkgName:        model.Package,
+			annotationArgs: model.AnnotationArgs,
+			root:           root,
+		})
+	}
+	return targets
+}
+
+// sameModules returns whether the modules of the cache are the given modules.
+func (c *discoveryCache) sameModules(modules []Module) bool {
+	if len(c.Modules) != len(modules) {
+		return false
+	}
+	for i := range modules {
+		if c.Modules[i] != modules[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheFields caches the fields of the file's models, discovered as targets.
+// The fields cached in the previous entry for the file are used while the
+// packages that they were resolved from are unchanged, unless reuse is false,
+// the others are resolved.
+func (f *cachedFile) cacheFields(previous cachedFile, targets []ModelTarget, resolver *typeResolver, reuse bool) {
+	for i, target := range targets {
+		var (
+			mt    = target.(*modelTarget)
+			model = &f.Models[i]
+		)
+
+		// The targets of an unchanged file are those of its previous
+		// entry, in the same order.
+		if reuse && previous.Hash == f.Hash && i < len(previous.Models) &&
+			previous.Models[i].fieldsValid(resolver) {
+			cached := previous.Models[i]
+			mt.fieldsOnce.Do(func() {
+				mt.fields = cached.Fields
+			})
+			mt.imports = cached.Imports
+			model.Fields, model.Packages, model.Imports = cached.Fields, cached.Packages, cached.Imports
+			continue
+		}
+
+		var (
+			fields  []Field
+			imports map[string]string
+			err     error
+		)
+		dirs := resolver.trackPackages(func() {
+			if fields, err = mt.Fields(); err == nil {
+				imports = mt.importedDirs(resolver, fields)
+			}
+		})
+		if err != nil {
+			// Reported if the fields are needed.
+			continue
+		}
+
+		model.Fields, model.Imports = fields, imports
+		model.Packages = make(map[string]string)
+		for _, dir := range dirs {
+			model.Packages[dir] = resolver.packageHash(dir)
+		}
+	}
+}
+
+// fieldsValid returns whether the model's cached fields are valid, i.e.
+// whether every package that they were resolved from is unchanged.
+func (m cachedModel) fieldsValid(resolver *typeResolver) bool {
+	if len(m.Packages) == 0 {
+		return false
+	}
+	for dir, hash := range m.Packages {
+		if resolver.packageHash(dir) != hash {
+			return false
+		}
+	}
+	return true
+}
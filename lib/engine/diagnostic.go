@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"sort"
+)
+
+// Severity is how serious a Diagnostic is.
+type Severity string
+
+const (
+	// SeverityError diagnostics mean that models may be missing or wrong,
+	// e.g. an annotated file can't be parsed.
+	SeverityError Severity = "error"
+	// SeverityWarning diagnostics don't affect the models that were
+	// identified, e.g. an unannotated file can't be parsed.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found with a source file during discovery. The
+// engine keeps going past problems, collecting a diagnostic for each, so
+// that callers can decide what to do about them.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	// File is the path of the file, relative to the engine root.
+	File string `json:"file"`
+	// Line and Column are the position of the problem in the file, or 0
+	// if it isn't about a specific position.
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	switch {
+	case d.Line > 0 && d.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+	case d.Line > 0:
+		return fmt.Sprintf("%s:%d: %s: %s", d.File, d.Line, d.Severity, d.Message)
+	default:
+		return fmt.Sprintf("%s: %s: %s", d.File, d.Severity, d.Message)
+	}
+}
+
+// HasErrors returns whether any of the diagnostics are errors.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// sortDiagnostics sorts the diagnostics by file and then position.
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		} else if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// newDiagnostic returns a diagnostic for the problem at the given position.
+func newDiagnostic(severity Severity, pos token.Position, message string) Diagnostic {
+	return Diagnostic{
+		Severity: severity,
+		File:     pos.Filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Message:  message,
+	}
+}
+
+// diagnosticsFromError returns a diagnostic for each of the problems in the
+// error, e.g. each syntax error when parsing a file.
+func diagnosticsFromError(severity Severity, fileName string, err error) []Diagnostic {
+	var errList scanner.ErrorList
+	if errors.As(err, &errList) {
+		var diagnostics []Diagnostic
+		for _, e := range errList {
+			diagnostics = append(diagnostics, newDiagnostic(severity, e.Pos, e.Msg))
+		}
+		return diagnostics
+	}
+
+	return []Diagnostic{{
+		Severity: severity,
+		File:     fileName,
+		Message:  err.Error(),
+	}}
+}
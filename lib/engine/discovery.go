@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFileName is the name of the file, at the engine root, that lists the
+// paths to leave out of model discovery using .gitignore syntax.
+const IgnoreFileName = ".autumnignore"
+
+// DefaultExcludedDirs are the names of directories that are never searched
+// for models, wherever they are. As with the go tool, directories whose names
+// start with `.` or `_` (e.g. `.git` and `.autumn`, which holds retrieved
+// frameworks and their example models) are also never searched.
+var DefaultExcludedDirs = []string{
+	"vendor",
+	"testdata",
+	"node_modules",
+}
+
+var (
+	// ErrBadPattern is returned when an include or exclude glob is
+	// malformed.
+	ErrBadPattern = errors.New("bad discovery pattern")
+)
+
+// discoveryRules decide which files, and directories, the engine searches
+// for models.
+type discoveryRules struct {
+	include []string
+	exclude []string
+	ignore  gitignore.Matcher
+}
+
+func newDiscoveryRules(root fs.FS, opts Options) (*discoveryRules, error) {
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if err := ValidateGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	rules := &discoveryRules{
+		include: opts.Include,
+		exclude: opts.Exclude,
+	}
+
+	data, err := fs.ReadFile(root, IgnoreFileName)
+	if os.IsNotExist(err) {
+		return rules, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	rules.ignore = gitignore.NewMatcher(patterns)
+
+	return rules, nil
+}
+
+// skipDir returns whether the directory, and everything in it, is left out
+// of discovery.
+func (r *discoveryRules) skipDir(dir string) bool {
+	if dir == "." {
+		return false
+	}
+
+	name := path.Base(dir)
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return true
+	}
+	for _, excluded := range DefaultExcludedDirs {
+		if name == excluded {
+			return true
+		}
+	}
+
+	return r.excluded(dir, true)
+}
+
+// skipFile returns whether the file is left out of discovery.
+func (r *discoveryRules) skipFile(fileName string) bool {
+	if r.excluded(fileName, false) {
+		return true
+	}
+	if len(r.include) == 0 {
+		return false
+	}
+	for _, pattern := range r.include {
+		if MatchGlob(pattern, fileName) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *discoveryRules) excluded(p string, isDir bool) bool {
+	if r.ignore != nil && r.ignore.Match(strings.Split(p, "/"), isDir) {
+		return true
+	}
+	for _, pattern := range r.exclude {
+		if MatchGlob(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateGlob returns an error wrapping ErrBadPattern if the glob is
+// malformed, see MatchGlob.
+func ValidateGlob(pattern string) error {
+	if len(pattern) == 0 || strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("%w %q: must be a relative path", ErrBadPattern, pattern)
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("%w %q: %s", ErrBadPattern, pattern, err)
+		}
+	}
+	return nil
+}
+
+// MatchGlob returns whether the slash separated path, relative to the engine
+// root, matches the glob. Globs are matched a path segment at a time, as
+// with path.Match, except that a `**` segment matches any number of
+// segments, e.g. `models/**` matches everything in the models directory and
+// `**/*_gen.go` matches generated files anywhere.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -1,14 +1,22 @@
 package engine
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
+	"io"
 	"io/fs"
 	"os"
+	pathpkg "path"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // Engine is our entrypoint into how we load model targets for any and all
@@ -17,6 +25,15 @@ type Engine interface {
 	// IdentifyModelTargets runs the engine identifying all identifiable
 	// ModelTargets.
 	IdentifyModelTargets() ([]ModelTarget, error)
+	// Diagnostics returns the problems found with source files while
+	// identifying model targets, ordered by file and position. Files with
+	// problems are skipped rather than stopping discovery.
+	Diagnostics() []Diagnostic
+	// Relationships returns the relationships between every model target,
+	// see Relationship, along with the problems found with them. As it
+	// needs the fields of every model target, it's only built when first
+	// needed.
+	Relationships() (*RelationshipGraph, error)
 }
 
 // ModelTarget is a model struct that has been identified that we want to
@@ -26,166 +43,782 @@ type ModelTarget interface {
 	Name() (string, error)
 	// PkgName returns the name of the package that the model is a part of.
 	PkgName() string
+	// ImportPath returns the import path of the package that the model is a
+	// part of, or "" if it isn't within a known module.
+	ImportPath() string
+	// Key returns the key that identifies the model within the project,
+	// see ModelKey.
+	Key() string
 	// GetDocumentText returns any comments attributed to the model target.
 	GetDocumentText() (string, error)
 	// GetModel returns the struct type node.
 	GetModel() (interface{}, error)
 	// GetLocation returns the location of the struct in its source file.
 	GetLocation() (string, error)
+	// GetFileName returns the path, relative to the engine root, of the
+	// source file that defines the model.
+	GetFileName() string
+	// TypeParams returns the type parameters of the generic type that the
+	// model was instantiated from, if any.
+	TypeParams() []TypeParam
+	// TypeArgs returns the type arguments that the model was instantiated
+	// with, in the order of TypeParams.
+	TypeArgs() []string
+	// Fields returns the fields of the model, including those promoted
+	// from the structs that it embeds, see Field.
+	Fields() ([]Field, error)
+	// Relationships returns the relationships from the model to other
+	// models, see Relationship.
+	Relationships() ([]Relationship, error)
+	// GetAnnotationArgs returns the arguments given to the model's
+	// annotation, e.g. `@Autumn:Model(operations="Retrieve,List")`.
+	GetAnnotationArgs() map[string]string
 	// ToTemplateVariables returns the model target as variables that can be
-	// used by engine consumers for generating code.
+	// used by engine consumers for generating code. They don't include the
+	// model's Relationships, which need every model's fields, so consumers
+	// add them only when they're used.
 	ToTemplateVariables() map[string]interface{}
 }
 
 // modelTarget is our local implementation of ModelTarget.
 type modelTarget struct {
-	astNode            ast.Node
+	name               string
+	typeName           string
+	typeParams         []TypeParam
+	typeArgs           []string
 	docText            string
-	typeNode           *ast.TypeSpec
-	structTypeNode     *ast.StructType
 	definitionPosition token.Position
 	pkgName            string
+	importPath         string
+	annotationArgs     map[string]string
+
+	// resolver resolves the structs that the model embeds.
+	resolver *typeResolver
+	// relationships are those between every model that the model was
+	// discovered with.
+	relationships *relationships
+
+	// The model's fields, which are resolved when first needed.
+	fieldsOnce sync.Once
+	fields     []Field
+	fieldsErr  error
+	// imports are the directories of the packages that the fields refer
+	// to, by the name that the model's file imports them with, for model
+	// targets whose fields were cached, see importedDir.
+	imports map[string]string
+
+	// The syntax tree of the model, which is parsed from root when first
+	// needed for model targets that were discovered from the cache.
+	root           fs.FS
+	once           sync.Once
+	loadErr        error
+	file           *ast.File
+	astNode        ast.Node
+	typeNode       *ast.TypeSpec
+	structTypeNode *ast.StructType
 }
 
 func (mt *modelTarget) Name() (string, error) {
-	if mt.typeNode == nil {
-		return "", errors.New("model target is missing a type node")
+	if len(mt.name) == 0 {
+		return "", errors.New("model target is missing a name")
 	}
-	return mt.typeNode.Name.String(), nil
+	return mt.name, nil
 }
 
 func (mt *modelTarget) PkgName() string {
 	return mt.pkgName
 }
 
+func (mt *modelTarget) ImportPath() string {
+	return mt.importPath
+}
+
+func (mt *modelTarget) Key() string {
+	return ModelKey(mt.importPath, mt.pkgName, mt.name)
+}
+
+// ModelKey returns the key that identifies a model within the project: its
+// import path and name, e.g. `example.com/project/models.User`. Models that
+// aren't within a module are identified by their package name instead, e.g.
+// `models.User`.
+func ModelKey(importPath, pkgName, name string) string {
+	if len(importPath) == 0 {
+		return pkgName + "." + name
+	}
+	return importPath + "." + name
+}
+
 func (mt *modelTarget) GetDocumentText() (string, error) {
 	return mt.docText, nil
 }
 func (mt *modelTarget) GetModel() (interface{}, error) {
+	if err := mt.load(); err != nil {
+		return nil, err
+	}
 	return mt.structTypeNode, nil
 }
+
+// load parses the model's syntax tree, unless it already has been.
+func (mt *modelTarget) load() error {
+	mt.once.Do(func() {
+		if mt.typeNode != nil {
+			return
+		}
+
+		fileName := mt.definitionPosition.Filename
+		contents, err := fs.ReadFile(mt.root, fileName)
+		if err != nil {
+			mt.loadErr = err
+			return
+		}
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, fileName, contents, parser.ParseComments)
+		if err != nil {
+			mt.loadErr = err
+			return
+		}
+
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok || typeSpec.Name.String() != mt.typeName ||
+					fset.Position(typeSpec.Pos()).Offset != mt.definitionPosition.Offset {
+					continue
+				}
+
+				mt.file, mt.astNode, mt.typeNode, mt.structTypeNode = f, genDecl, typeSpec, structType
+				if genDecl.Lparen.IsValid() {
+					mt.astNode = typeSpec
+				}
+				return
+			}
+		}
+		mt.loadErr = fmt.Errorf("%s: model %s changed since it was discovered", mt.definitionPosition, mt.name)
+	})
+	return mt.loadErr
+}
+
 func (mt *modelTarget) GetLocation() (string, error) {
 	return mt.definitionPosition.String(), nil
 }
+func (mt *modelTarget) GetFileName() string {
+	return mt.definitionPosition.Filename
+}
+func (mt *modelTarget) TypeParams() []TypeParam {
+	return mt.typeParams
+}
+func (mt *modelTarget) TypeArgs() []string {
+	return mt.typeArgs
+}
+
+// modelType returns the type of the model as it would be written in its
+// package, e.g. `Page[User]` for instantiated generic models.
+func (mt *modelTarget) modelType() string {
+	if len(mt.typeArgs) == 0 {
+		return mt.typeName
+	}
+	return fmt.Sprintf("%s[%s]", mt.typeName, strings.Join(mt.typeArgs, ", "))
+}
+func (mt *modelTarget) Fields() ([]Field, error) {
+	// Fields are resolved once per model, as both templates and the
+	// relationships of every model need them.
+	mt.fieldsOnce.Do(func() {
+		mt.fields, mt.fieldsErr = mt.resolveFields()
+	})
+	return mt.fields, mt.fieldsErr
+}
+
+func (mt *modelTarget) resolveFields() ([]Field, error) {
+	if err := mt.load(); err != nil {
+		return nil, err
+	}
+
+	resolver := mt.resolver
+	if resolver == nil {
+		// Without a project, embedded structs can't be resolved.
+		resolver = newTypeResolver(nil, nil, nil, false)
+	}
+	pkg := resolver.packageIn(pathpkg.Dir(mt.definitionPosition.Filename))
+	fields := resolver.fields(mt.structTypeNode, mt.file, pkg)
+	if len(mt.typeArgs) > 0 {
+		for i := range fields {
+			substituted := substituteTypeArgs(fields[i].Type, mt.typeParams, mt.typeArgs)
+			if substituted == fields[i].Type {
+				continue
+			}
+			fields[i].Type = substituted
+
+			// Type arguments are written as the model's file refers to
+			// them, e.g. Page[Status], and can be enums themselves.
+			if expr, err := parser.ParseExpr(substituted); err == nil {
+				fields[i].Enum = resolver.enum(expr, mt.file, pkg, "")
+			}
+		}
+	}
+	return fields, nil
+}
+func (mt *modelTarget) Relationships() ([]Relationship, error) {
+	rels := mt.relationships
+	if rels == nil {
+		// Without other models, the model can only refer to itself.
+		rels = &relationships{targets: []ModelTarget{mt}, resolver: mt.resolver}
+	}
+	graph, err := rels.build()
+	if err != nil {
+		return nil, err
+	}
+	return graph.Of(mt.Key()), nil
+}
+func (mt *modelTarget) GetAnnotationArgs() map[string]string {
+	return mt.annotationArgs
+}
 func (mt *modelTarget) ToTemplateVariables() map[string]interface{} {
-	return map[string]interface{}{
-		"Name":             mt.typeNode.Name.String(),
+	vars := map[string]interface{}{
+		"Name":             mt.name,
 		"ModelPackageName": mt.pkgName,
+		"ModelImportPath":  mt.importPath,
+		"ModelType":        mt.modelType(),
+		"TypeParams":       mt.typeParams,
+		"TypeArgs":         mt.typeArgs,
 	}
+	// Consumers that need to know why the fields are missing can call
+	// Fields themselves.
+	if fields, err := mt.Fields(); err == nil {
+		vars["Fields"] = fields
+		vars["Enums"] = enumsOf(fields)
+	}
+	return vars
 }
 
 type engine struct {
-	root         fs.FS
-	modelEntries []ModelTarget
+	root          fs.FS
+	modelEntries  []ModelTarget
+	diagnostics   []Diagnostic
+	relationships *relationships
 }
 
 var (
 	debugLoggingOn = strings.ToLower(os.Getenv("AUTUMN_DEBUG_LOGGING_ON")) == "true"
 )
 
+// Options configure how an engine discovers model targets.
+type Options struct {
+	// Modules are the modules of the project, which give model targets
+	// their import paths. When nil they're discovered with DiscoverModules.
+	Modules []Module
+	// Workspace limits discovery to files within Modules, as only they
+	// are part of a go.work workspace.
+	Workspace bool
+	// Include, when set, limits discovery to the files matching at least
+	// one of its globs, see MatchGlob.
+	Include []string
+	// Exclude leaves the files and directories matching any of its globs
+	// out of discovery, as are DefaultExcludedDirs and the paths listed in
+	// the root's IgnoreFileName.
+	Exclude []string
+
+	// GOOS and GOARCH are the platform whose build constraints files must
+	// satisfy, as with `go build`. They default to the current platform.
+	GOOS   string
+	GOARCH string
+	// BuildTags are the additional build tags that are satisfied, as with
+	// `go build -tags`.
+	BuildTags []string
+	// Tests includes _test.go files in discovery, which are otherwise left
+	// out so that e.g. example models in tests aren't generated for.
+	Tests bool
+
+	// Workers is the number of files that are read and parsed at once,
+	// defaulting to GOMAXPROCS.
+	Workers int
+	// CacheDir, when set, is the directory that what was discovered in
+	// each file, and the fields of its models, are cached in between runs,
+	// keyed by the file's path and content hash, so that unchanged files
+	// aren't parsed again.
+	CacheDir string
+
+	// Report, when set, is called with the problems found after discovery,
+	// i.e. those with the relationships between models, once they're
+	// built (see Engine.Relationships).
+	Report func(Diagnostic)
+}
+
 // NewEngine returns a new engine rooted at the goven fs.FS root.
 // It returns an error if it fails to walk the directory at the root of the
 // fs.FS.
 func NewEngine(root fs.FS) (Engine, error) {
+	return NewEngineWithOptions(root, Options{})
+}
+
+// NewEngineWithOptions returns a new engine rooted at the given fs.FS root,
+// discovering model targets as configured by the options.
+func NewEngineWithOptions(root fs.FS, opts Options) (Engine, error) {
 	var (
 		fileEntries  = make(map[string]fs.DirEntry)
 		modelEntries []ModelTarget
+		diagnostics  []Diagnostic
 	)
 
+	if opts.Modules == nil {
+		modules, workspace, moduleDiagnostics, err := DiscoverModules(root)
+		if err != nil {
+			return nil, err
+		}
+		opts.Modules, opts.Workspace = modules, workspace
+		diagnostics = append(diagnostics, moduleDiagnostics...)
+	}
+
+	rules, err := newDiscoveryRules(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	ctxt := buildContext(root, opts)
+	resolver := newTypeResolver(root, opts.Modules, ctxt, opts.Tests)
+
 	// Walk the directory to identify all go files.
 	if err := fs.WalkDir(root, ".", func(path string, d fs.DirEntry, err error) error {
-		if strings.HasSuffix(path, ".go") {
-			fileEntries[path] = d
+		if err != nil && path == "." {
+			return err
+		} else if err != nil {
+			// Keep going without whatever we couldn't read.
+			diagnostics = append(diagnostics, diagnosticsFromError(SeverityWarning, path, err)...)
+			return nil
+		}
+
+		if d.IsDir() {
+			if rules.skipDir(path) {
+				if debugLoggingOn {
+					fmt.Printf("skipping directory %q\n", path)
+				}
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(path, ".go") || rules.skipFile(path) {
+			return nil
+		} else if strings.HasSuffix(path, "_test.go") && !opts.Tests {
+			return nil
+		} else if _, ok := moduleFor(opts.Modules, path); opts.Workspace && !ok {
+			// Not part of any of the workspace's modules.
+			return nil
+		}
+
+		// Leave out files for other platforms, or that need other build
+		// tags, as they'd e.g. define the same model twice.
+		if match, err := ctxt.MatchFile(pathpkg.Dir(path), pathpkg.Base(path)); err != nil {
+			diagnostics = append(diagnostics, diagnosticsFromError(
+				severityFor(root, path),
+				path,
+				fmt.Errorf("failed to check build constraints: %w", err),
+			)...)
+			return nil
+		} else if !match {
 			if debugLoggingOn {
-				fmt.Printf("identified file %q as a go file\n", path)
+				fmt.Printf("skipping file %q due to its build constraints\n", path)
 			}
+			return nil
+		}
+
+		fileEntries[path] = d
+		if debugLoggingOn {
+			fmt.Printf("identified file %q as a go file\n", path)
 		}
 		return nil
 	}); err != nil {
 		return nil, err
 	}
 
-	// Now process files
-	for fileName, _ := range fileEntries {
-		fileContents, err := fs.ReadFile(root, fileName)
-		if err != nil {
-			return nil, err
+	// Now process files, in a stable order.
+	var fileNames []string
+	for fileName := range fileEntries {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	var cache *discoveryCache
+	if len(opts.CacheDir) > 0 {
+		cache = readCache(opts.CacheDir)
+	}
+
+	results := discoverFiles(root, fileNames, cache, opts.Workers)
+
+	var cached = make(map[string]cachedFile)
+	for i, fileName := range fileNames {
+		result := results[i]
+		diagnostics = append(diagnostics, result.diagnostics...)
+
+		for _, target := range result.targets {
+			target.(*modelTarget).resolver = resolver
+		}
+		if module, ok := moduleFor(opts.Modules, fileName); ok {
+			importPath := module.importPath(pathpkg.Dir(fileName))
+			for _, target := range result.targets {
+				target.(*modelTarget).importPath = importPath
+			}
 		}
 
-		targets, err := modelTargetFromText(fileName, string(fileContents))
-		if err != nil {
-			return nil, err
+		// Fields are resolved up front when caching, so that the next
+		// run doesn't need to parse the packages that they're resolved
+		// from.
+		if cache != nil && len(result.hash) > 0 {
+			entry := cacheFile(result.hash, result.targets, result.diagnostics)
+			entry.cacheFields(cache.Files[fileName], result.targets, resolver, cache.sameModules(opts.Modules))
+			cached[fileName] = entry
+		}
+
+		modelEntries = append(modelEntries, result.targets...)
+	}
+
+	// Only the files that were discovered this time are kept, so that the
+	// cache doesn't grow with files that no longer exist.
+	if cache != nil {
+		cache.Modules = opts.Modules
+		cache.Files = cached
+		if err := cache.write(opts.CacheDir); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				File:     filepath.Join(opts.CacheDir, CacheFileName),
+				Message:  fmt.Sprintf("failed to write discovery cache: %s", err),
+			})
 		}
+	}
+
+	sortModelTargets(modelEntries)
+	sortDiagnostics(diagnostics)
 
-		modelEntries = append(modelEntries, targets...)
+	rels := &relationships{
+		targets:  modelEntries,
+		resolver: resolver,
+		report:   opts.Report,
+	}
+	for _, target := range modelEntries {
+		target.(*modelTarget).relationships = rels
 	}
 
 	return &engine{
-		root:         root,
-		modelEntries: modelEntries,
+		root:          root,
+		modelEntries:  modelEntries,
+		diagnostics:   diagnostics,
+		relationships: rels,
 	}, nil
 }
 
+// fileResult is what was discovered in a single file.
+type fileResult struct {
+	targets     []ModelTarget
+	diagnostics []Diagnostic
+	// hash is the hash of the file's content, if it was parsed or found in
+	// the cache.
+	hash string
+}
+
+// discoverFiles discovers the model targets in each of the files, using up to
+// the given number of workers, and returns the results in the same order as
+// the files.
+func discoverFiles(root fs.FS, fileNames []string, cache *discoveryCache, workers int) []fileResult {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		results = make([]fileResult, len(fileNames))
+		indexes = make(chan int)
+		wg      sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = discoverFile(root, fileNames[i], cache)
+			}
+		}()
+	}
+
+	for i := range fileNames {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// discoverFile discovers the model targets in a single file, from the cache if
+// the file hasn't changed. The cache is only read from, so it can be shared
+// by workers.
+func discoverFile(root fs.FS, fileName string, cache *discoveryCache) fileResult {
+	contents, err := fs.ReadFile(root, fileName)
+	if err != nil {
+		return fileResult{
+			diagnostics: diagnosticsFromError(SeverityWarning, fileName, err),
+		}
+	}
+
+	hash := contentHashOf(contents)
+	if cache != nil {
+		if entry, ok := cache.Files[fileName]; ok && entry.Hash == hash {
+			return fileResult{
+				targets:     entry.targets(root, fileName),
+				diagnostics: entry.Diagnostics,
+				hash:        hash,
+			}
+		}
+	}
+
+	// Most files in a project don't define models, so they're only
+	// checked for syntax errors, without keeping their comments or
+	// resolving their identifiers.
+	if !mayBeAnnotated(contents) {
+		var diagnostics []Diagnostic
+		if _, err := parser.ParseFile(token.NewFileSet(), fileName, contents, parser.SkipObjectResolution); err != nil {
+			diagnostics = diagnosticsFromError(SeverityWarning, fileName, err)
+		}
+		return fileResult{
+			diagnostics: diagnostics,
+			hash:        hash,
+		}
+	}
+
+	targets, diagnostics := modelTargetFromText(fileName, string(contents))
+	return fileResult{
+		targets:     targets,
+		diagnostics: diagnostics,
+		hash:        hash,
+	}
+}
+
+// mayBeAnnotated returns whether the file contents may contain annotations,
+// or typos of them, i.e. whether `@autumn` appears in any case.
+func mayBeAnnotated(contents []byte) bool {
+	prefix := []byte(autumnAnnotationPrefix)
+	for {
+		i := bytes.IndexByte(contents, '@')
+		if i < 0 {
+			return false
+		}
+		contents = contents[i:]
+		if len(contents) >= len(prefix) && bytes.EqualFold(contents[:len(prefix)], prefix) {
+			return true
+		}
+		contents = contents[1:]
+	}
+}
+
+// sortModelTargets sorts the targets by import path (or directory, for
+// targets outside of a module) and then by position, so that the engine
+// identifies them in the same order on every run.
+func sortModelTargets(targets []ModelTarget) {
+	sort.SliceStable(targets, func(i, j int) bool {
+		a, b := targets[i].(*modelTarget), targets[j].(*modelTarget)
+		if pathA, pathB := a.sortPath(), b.sortPath(); pathA != pathB {
+			return pathA < pathB
+		}
+
+		posA, posB := a.definitionPosition, b.definitionPosition
+		if posA.Filename != posB.Filename {
+			return posA.Filename < posB.Filename
+		}
+		return posA.Offset < posB.Offset
+	})
+}
+
+func (mt *modelTarget) sortPath() string {
+	if len(mt.importPath) > 0 {
+		return mt.importPath
+	}
+	return pathpkg.Dir(mt.definitionPosition.Filename)
+}
+
+// buildContext returns the build context that files are matched against,
+// reading files from root.
+func buildContext(root fs.FS, opts Options) *build.Context {
+	ctxt := build.Default
+	if len(opts.GOOS) > 0 {
+		ctxt.GOOS = opts.GOOS
+	}
+	if len(opts.GOARCH) > 0 {
+		ctxt.GOARCH = opts.GOARCH
+	}
+	ctxt.BuildTags = opts.BuildTags
+	// Files using cgo can still define models, and whether cgo is enabled
+	// depends on the environment, which would make discovery depend on it
+	// too.
+	ctxt.CgoEnabled = true
+
+	ctxt.JoinPath = pathpkg.Join
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		return root.Open(name)
+	}
+	return &ctxt
+}
+
 var autumnModelIdentifier = "@Autumn:Model"
 
+// autumnFieldIdentifier annotates the fields of models, e.g. with the model
+// that they refer to.
+var autumnFieldIdentifier = "@Autumn:Field"
+
+// autumnAnnotationPrefix prefixes every autumn annotation. Files containing
+// it are considered annotated.
+var autumnAnnotationPrefix = "@Autumn"
+
+// severityFor returns the severity of problems with the file: errors for
+// annotated files, whose models would otherwise go missing, and warnings
+// for everything else.
+func severityFor(root fs.FS, fileName string) Severity {
+	contents, err := fs.ReadFile(root, fileName)
+	if err == nil && !strings.Contains(string(contents), autumnAnnotationPrefix) {
+		return SeverityWarning
+	}
+	return SeverityError
+}
+
+func severityForText(text string) Severity {
+	if strings.Contains(text, autumnAnnotationPrefix) {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
 // modelTargetFromText creates a model target from source code when it finds
 // a struct with the given annotation.
 //
 // NOTE: in a future iteration, it would be useful to also support providing
 // model struct names via a config file in addition to using annotations.
-func modelTargetFromText(name, text string) ([]ModelTarget, error) {
-	var targets []ModelTarget
+//
+// Problems with the file, e.g. syntax errors, are returned as diagnostics
+// along with the targets that could still be identified.
+func modelTargetFromText(name, text string) ([]ModelTarget, []Diagnostic) {
+	var (
+		targets     []ModelTarget
+		diagnostics []Diagnostic
+	)
 
 	// Parse the file
 	fset := token.NewFileSet() // positions are relative to fset
 	f, err := parser.ParseFile(fset, name, text, parser.ParseComments)
 	if err != nil {
-		return nil, err
+		return nil, diagnosticsFromError(severityForText(text), name, err)
 	}
 
-	// Process the comments in the file
+	// Process the comments in the file, in the order that they appear as
+	// iterating over the comment map itself is random.
 	cmap := ast.NewCommentMap(fset, f, f.Comments)
-	for node, comments := range cmap {
+	var nodes []ast.Node
+	for node := range cmap {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Pos() < nodes[j].Pos()
+	})
+
+	// Specs are described along with the kind of declaration they're in.
+	var decls = make(map[ast.Spec]token.Token)
+	for _, decl := range f.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok {
+			for _, spec := range genDecl.Specs {
+				decls[spec] = genDecl.Tok
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		comments := cmap[node]
 		for _, commentList := range comments {
 			for _, comment := range commentList.List {
-				if strings.Contains(comment.Text, autumnModelIdentifier) {
-					decl, ok := node.(*ast.GenDecl)
-					if !ok {
-						if debugLoggingOn {
-							fmt.Println("not a valid GenDecl")
-						}
+				annotations, offsets := findAnnotations(comment.Text)
+				for i, annotation := range annotations {
+					pos := annotationPosition(fset, comment, offsets[i])
+					if suggestion, ok := suggestAnnotation(annotation); ok && len(suggestion) > 0 {
+						diagnostics = append(diagnostics, newDiagnostic(
+							SeverityWarning,
+							pos,
+							fmt.Sprintf("unknown annotation %s, did you mean %s?", annotation, suggestion),
+						))
+						continue
+					} else if ok {
+						diagnostics = append(diagnostics, newDiagnostic(
+							SeverityWarning,
+							pos,
+							fmt.Sprintf("unknown annotation %s", annotation),
+						))
 						continue
-					} else if decl.Tok != token.TYPE {
-						if debugLoggingOn {
-							fmt.Println("not a type declaration")
+					} else if annotation == autumnFieldIdentifier {
+						diagnostics = append(diagnostics, checkFieldAnnotation(node, comment.Text[offsets[i]:], pos)...)
+						continue
+					} else if annotation != autumnModelIdentifier {
+						continue
+					}
+
+					// Models are struct types, annotated either on their
+					// declaration or, within a type block, on their spec.
+					var specs []ast.Spec
+					switch n := node.(type) {
+					case *ast.GenDecl:
+						if n.Tok == token.TYPE {
+							specs = n.Specs
 						}
+					case *ast.TypeSpec:
+						specs = []ast.Spec{n}
+					}
+					if len(specs) == 0 {
+						diagnostics = append(diagnostics, newDiagnostic(
+							SeverityWarning,
+							pos,
+							fmt.Sprintf("%s on %s", annotation, describeMisplaced(node, decls)),
+						))
+						continue
+					}
+
+					args, err := parseAnnotationArgs(comment.Text[offsets[i]:], autumnModelIdentifier)
+					if err != nil {
+						diagnostics = append(diagnostics, newDiagnostic(
+							SeverityError,
+							pos,
+							err.Error(),
+						))
 						continue
 					}
 
-					for _, spec := range decl.Specs {
-						typeSpec, ok := spec.(*ast.TypeSpec)
-						if !ok {
-							if debugLoggingOn {
-								fmt.Println("not a struct type")
-							}
+					for _, spec := range specs {
+						typeSpec := spec.(*ast.TypeSpec)
+						structType, ok := typeSpec.Type.(*ast.StructType)
+						if !ok || typeSpec.Assign.IsValid() {
+							diagnostics = append(diagnostics, newDiagnostic(
+								SeverityWarning,
+								pos,
+								fmt.Sprintf("%s on %s", annotation, describeMisplaced(typeSpec, decls)),
+							))
 							continue
 						}
 
-						structType, ok := typeSpec.Type.(*ast.StructType)
-						if !ok {
-							if debugLoggingOn {
-								fmt.Println("type spec not for a struct")
-							}
+						inst, err := instantiate(typeSpec, args)
+						if err != nil {
+							diagnostics = append(diagnostics, newDiagnostic(SeverityError, pos, err.Error()))
 							continue
 						}
 
 						targets = append(targets, &modelTarget{
-							astNode:            node,
+							name:               inst.name,
+							typeName:           typeSpec.Name.String(),
+							typeParams:         inst.typeParams,
+							typeArgs:           inst.typeArgs,
 							docText:            typeSpec.Doc.Text(),
-							typeNode:           typeSpec,
-							structTypeNode:     structType,
 							definitionPosition: fset.Position(typeSpec.Pos()),
 							pkgName:            f.Name.String(),
+							annotationArgs:     args,
+							file:               f,
+							astNode:            node,
+							typeNode:           typeSpec,
+							structTypeNode:     structType,
 						})
 					}
 				}
@@ -193,9 +826,17 @@ func modelTargetFromText(name, text string) ([]ModelTarget, error) {
 		}
 	}
 
-	return targets, nil
+	return targets, diagnostics
 }
 
 func (e *engine) IdentifyModelTargets() ([]ModelTarget, error) {
 	return e.modelEntries, nil
 }
+
+func (e *engine) Diagnostics() []Diagnostic {
+	return e.diagnostics
+}
+
+func (e *engine) Relationships() (*RelationshipGraph, error) {
+	return e.relationships.build()
+}
@@ -1,6 +1,13 @@
 package engine
 
 import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -65,3 +72,1259 @@ func TestNewEngine(t *testing.T) {
 		t.Error("got unexpected model name: ", modelName)
 	}
 }
+
+func TestParseAnnotationArgs(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected map[string]string
+		err      bool
+	}{
+		{"// @Autumn:Model", map[string]string{}, false},
+		{"// @Autumn:Model()", map[string]string{}, false},
+		{
+			`// @Autumn:Model(operations="Retrieve,List")`,
+			map[string]string{"operations": "Retrieve,List"},
+			false,
+		},
+		{
+			`// @Autumn:Model( a = 1 , b="x)" )`,
+			map[string]string{"a": "1", "b": "x)"},
+			false,
+		},
+		{`// @Autumn:Model(operations`, nil, true},
+		{`// @Autumn:Model(operations="List"`, nil, true},
+		{`// @Autumn:Model(=List)`, nil, true},
+	}
+
+	for _, test := range tests {
+		args, err := parseAnnotationArgs(test.text, autumnModelIdentifier)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.text)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected err: %v", test.text, err)
+		} else if !reflect.DeepEqual(args, test.expected) {
+			t.Errorf("%s: expected %v, found %v", test.text, test.expected, args)
+		}
+	}
+}
+
+func TestNewEngineWorkspace(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.work": &fstest.MapFile{
+			Data: []byte(`go 1.18
+
+use ./users // the users service
+use (
+	./orders
+	"./billing"
+	../shared
+)
+`),
+		},
+		"users/go.mod": &fstest.MapFile{
+			Data: []byte("module example.com/users\n"),
+		},
+		"users/models/model.go": &fstest.MapFile{
+			Data: []byte(modelGoFile),
+		},
+		"orders/go.mod": &fstest.MapFile{
+			Data: []byte("module example.com/orders\n"),
+		},
+		"orders/model.go": &fstest.MapFile{
+			Data: []byte(modelGoFile),
+		},
+		"billing/README": &fstest.MapFile{
+			Data: []byte(textFile),
+		},
+		"scratch/model.go": &fstest.MapFile{
+			Data: []byte(modelGoFile),
+		},
+	}
+
+	eng, err := NewEngine(rootFS)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	modelTargets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	var importPaths = make(map[string]string)
+	for _, model := range modelTargets {
+		importPaths[model.GetFileName()] = model.ImportPath()
+	}
+
+	expected := map[string]string{
+		"users/models/model.go": "example.com/users/models",
+		"orders/model.go":       "example.com/orders",
+	}
+	if !reflect.DeepEqual(importPaths, expected) {
+		t.Errorf("expected import paths %v, found %v", expected, importPaths)
+	}
+
+	// Modules outside of the root are skipped with a warning.
+	expectedDiagnostics := []Diagnostic{{
+		Severity: SeverityWarning,
+		File:     "go.work",
+		Line:     7,
+		Column:   2,
+		Message:  "module ../shared is outside of the project root, its models aren't discovered",
+	}}
+	if diagnostics := eng.Diagnostics(); !reflect.DeepEqual(diagnostics, expectedDiagnostics) {
+		t.Errorf("expected diagnostics %v, found %v", expectedDiagnostics, diagnostics)
+	}
+}
+
+func TestNewEngineModule(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.mod": &fstest.MapFile{
+			Data: []byte("module example.com/project\n"),
+		},
+		"model.go": &fstest.MapFile{
+			Data: []byte(modelGoFile),
+		},
+		"internal/models/model.go": &fstest.MapFile{
+			Data: []byte(modelGoFile),
+		},
+	}
+
+	eng, err := NewEngine(rootFS)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	modelTargets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	var importPaths = make(map[string]string)
+	for _, model := range modelTargets {
+		importPaths[model.GetFileName()] = model.ImportPath()
+	}
+
+	expected := map[string]string{
+		"model.go":                 "example.com/project",
+		"internal/models/model.go": "example.com/project/internal/models",
+	}
+	if !reflect.DeepEqual(importPaths, expected) {
+		t.Errorf("expected import paths %v, found %v", expected, importPaths)
+	}
+}
+
+func TestIdentifyModelTargetsOrder(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.mod": &fstest.MapFile{
+			Data: []byte("module example.com/project\n"),
+		},
+		"zoo/models.go": &fstest.MapFile{
+			Data: []byte(`package zoo
+
+// @Autumn:Model
+type Zebra struct{}
+
+// @Autumn:Model
+type Aardvark struct{}
+`),
+		},
+		"models/b.go": &fstest.MapFile{
+			Data: []byte(`package models
+
+// @Autumn:Model
+type User struct{}
+`),
+		},
+		"models/a.go": &fstest.MapFile{
+			Data: []byte(`package models
+
+// @Autumn:Model
+type Order struct{}
+
+// @Autumn:Model
+type Account struct{}
+`),
+		},
+	}
+
+	expected := []string{
+		"example.com/project/models.Order",
+		"example.com/project/models.Account",
+		"example.com/project/models.User",
+		"example.com/project/zoo.Zebra",
+		"example.com/project/zoo.Aardvark",
+	}
+
+	// Map iteration is random, so a few runs should catch any ordering
+	// that depends on it.
+	for i := 0; i < 10; i++ {
+		eng, err := NewEngine(rootFS)
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+
+		modelTargets, err := eng.IdentifyModelTargets()
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+
+		var actual []string
+		for _, model := range modelTargets {
+			name, _ := model.Name()
+			actual = append(actual, model.ImportPath()+"."+name)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("expected models in order %v, found %v", expected, actual)
+		}
+	}
+}
+
+func TestNewEngineDiscoveryRules(t *testing.T) {
+	model := func(pkg string) *fstest.MapFile {
+		return &fstest.MapFile{
+			Data: []byte("package " + pkg + "\n\n// @Autumn:Model\ntype Model struct{}\n"),
+		}
+	}
+
+	var rootFS = fstest.MapFS{
+		IgnoreFileName: &fstest.MapFile{
+			Data: []byte("# generated examples\nexamples/\n*_ignored.go\n"),
+		},
+		"models/model.go":                 model("models"),
+		"models/model_ignored.go":         model("models"),
+		"models/model_gen.go":             model("models"),
+		"models/nested/model.go":          model("nested"),
+		"examples/model.go":               model("examples"),
+		"vendor/example.com/dep/model.go": model("dep"),
+		"testdata/model.go":               model("testdata"),
+		"node_modules/pkg/model.go":       model("pkg"),
+		".autumn/frameworks/fw/model.go":  model("fw"),
+		"_scratch/model.go":               model("scratch"),
+		"internal/legacy/model.go":        model("legacy"),
+		"internal/current/model.go":       model("current"),
+		"cmd/tool/main.go":                model("main"),
+	}
+
+	var tests = []struct {
+		name     string
+		opts     Options
+		expected []string
+	}{
+		{
+			name: "defaults and ignore file",
+			expected: []string{
+				"cmd/tool/main.go",
+				"internal/current/model.go",
+				"internal/legacy/model.go",
+				"models/model.go",
+				"models/model_gen.go",
+				"models/nested/model.go",
+			},
+		},
+		{
+			name: "include and exclude globs",
+			opts: Options{
+				Include: []string{"models/**", "internal/**"},
+				Exclude: []string{"**/*_gen.go", "internal/legacy", "models/nested"},
+			},
+			expected: []string{
+				"internal/current/model.go",
+				"models/model.go",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		eng, err := NewEngineWithOptions(rootFS, test.opts)
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+
+		modelTargets, err := eng.IdentifyModelTargets()
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+
+		var actual []string
+		for _, model := range modelTargets {
+			actual = append(actual, model.GetFileName())
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("[%s] expected files %v, found %v", test.name, test.expected, actual)
+		}
+	}
+
+	if _, err := NewEngineWithOptions(rootFS, Options{Exclude: []string{"[a-"}}); !errors.Is(err, ErrBadPattern) {
+		t.Error("expected ErrBadPattern, found: ", err)
+	}
+}
+
+func TestNewEngineBuildConstraints(t *testing.T) {
+	model := func(header string) *fstest.MapFile {
+		return &fstest.MapFile{
+			Data: []byte(header + "package models\n\n// @Autumn:Model\ntype Model struct{}\n"),
+		}
+	}
+
+	var rootFS = fstest.MapFS{
+		"models/model.go":           model(""),
+		"models/model_test.go":      model(""),
+		"models/model_linux.go":     model(""),
+		"models/model_windows.go":   model(""),
+		"models/model_arm64.go":     model(""),
+		"models/integration.go":     model("//go:build integration\n\n"),
+		"models/legacy.go":          model("// +build ignore\n\n"),
+		"models/not_integration.go": model("//go:build !integration\n\n"),
+		"models/linux_and_integ.go": model("//go:build linux && integration\n\n"),
+	}
+
+	var tests = []struct {
+		name     string
+		opts     Options
+		expected []string
+	}{
+		{
+			name: "linux",
+			opts: Options{GOOS: "linux", GOARCH: "amd64"},
+			expected: []string{
+				"models/model.go",
+				"models/model_linux.go",
+				"models/not_integration.go",
+			},
+		},
+		{
+			name: "windows with tags and tests",
+			opts: Options{GOOS: "windows", GOARCH: "arm64", BuildTags: []string{"integration"}, Tests: true},
+			expected: []string{
+				"models/integration.go",
+				"models/model.go",
+				"models/model_arm64.go",
+				"models/model_test.go",
+				"models/model_windows.go",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		eng, err := NewEngineWithOptions(rootFS, test.opts)
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+
+		modelTargets, err := eng.IdentifyModelTargets()
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+
+		var actual []string
+		for _, model := range modelTargets {
+			actual = append(actual, model.GetFileName())
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("[%s] expected files %v, found %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestNewEngineDiagnostics(t *testing.T) {
+	var tests = []struct {
+		name        string
+		files       fstest.MapFS
+		models      int
+		diagnostics []Diagnostic
+	}{
+		{
+			name: "broken unannotated file",
+			files: fstest.MapFS{
+				"models/model.go":  &fstest.MapFile{Data: []byte(modelGoFile)},
+				"scripts/wip.go":   &fstest.MapFile{Data: []byte("package scripts\n\nfunc main() {\n")},
+				"models/helper.go": &fstest.MapFile{Data: []byte("package models\n\nfunc helper() {}\n")},
+			},
+			models: 1,
+			diagnostics: []Diagnostic{
+				{Severity: SeverityWarning, File: "scripts/wip.go", Line: 3, Column: 15, Message: "expected '}', found 'EOF'"},
+			},
+		},
+		{
+			name: "broken annotated file",
+			files: fstest.MapFS{
+				"models/model.go":  &fstest.MapFile{Data: []byte(modelGoFile)},
+				"models/broken.go": &fstest.MapFile{Data: []byte("package models\n\n// @Autumn:Model\ntype Broken struct {\n\tID string\n")},
+			},
+			models: 1,
+			diagnostics: []Diagnostic{
+				{Severity: SeverityError, File: "models/broken.go", Line: 5, Column: 12, Message: "expected '}', found 'EOF'"},
+			},
+		},
+		{
+			name: "invalid annotation",
+			files: fstest.MapFS{
+				"models/model.go": &fstest.MapFile{Data: []byte("package models\n\n// @Autumn:Model(operations)\ntype Model struct{}\n")},
+			},
+			models: 0,
+			diagnostics: []Diagnostic{
+				{Severity: SeverityError, File: "models/model.go", Line: 3, Column: 4, Message: "malformed @Autumn:Model arguments: expected key=value"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		eng, err := NewEngine(test.files)
+		if err != nil {
+			t.Fatalf("[%s] unexpected err: %s", test.name, err)
+		}
+
+		modelTargets, err := eng.IdentifyModelTargets()
+		if err != nil {
+			t.Fatalf("[%s] unexpected err: %s", test.name, err)
+		} else if len(modelTargets) != test.models {
+			t.Errorf("[%s] expected %d models, found %d", test.name, test.models, len(modelTargets))
+		}
+
+		if diagnostics := eng.Diagnostics(); !reflect.DeepEqual(diagnostics, test.diagnostics) {
+			t.Errorf("[%s] expected diagnostics %v, found %v", test.name, test.diagnostics, diagnostics)
+		}
+	}
+}
+
+func TestModelTargetFromTextAnnotationDiagnostics(t *testing.T) {
+	const text = `package models
+
+// @Autumn:Model
+type ID string
+
+// @Autumn:Model
+type Alias = Model
+
+// @Autumn:Model
+type Store interface{}
+
+// @Autumn:Model
+func NewModel() {}
+
+// @Autumn:Model
+var defaultModel Model
+
+type Model struct {
+	// @Autumn:Model
+	Name string
+}
+
+// @autumn:model
+type Typo struct{}
+
+// @Autumn:Modle
+type OtherTypo struct{}
+
+// @Autumn:Thing
+type Unknown struct{}
+
+type (
+	// @Autumn:Model
+	Grouped struct{}
+)
+
+// Frobnicate is documented with @param, which isn't ours.
+func Frobnicate() {}
+
+// @Autumn:Field(ref=User)
+func Helper() {}
+
+type Owned struct {
+	OwnerID string // @Autumn:Field(model=User)
+}
+`
+
+	targets, diagnostics := modelTargetFromText("models/model.go", text)
+
+	var names []string
+	for _, target := range targets {
+		name, _ := target.Name()
+		names = append(names, name)
+	}
+	if !reflect.DeepEqual(names, []string{"Grouped"}) {
+		t.Error("expected only Grouped to be a model, found: ", names)
+	}
+
+	var expected = []string{
+		"models/model.go:3:4: warning: @Autumn:Model on type `ID string`, only struct types are supported",
+		"models/model.go:6:4: warning: @Autumn:Model on type alias `Alias = Model`, only struct types are supported",
+		"models/model.go:9:4: warning: @Autumn:Model on interface `Store`, only struct types are supported",
+		"models/model.go:12:4: warning: @Autumn:Model on func `NewModel`, annotate a struct type instead",
+		"models/model.go:15:4: warning: @Autumn:Model on var `defaultModel`, only struct types are supported",
+		"models/model.go:19:5: warning: @Autumn:Model on field `Name`, annotate the struct type instead",
+		"models/model.go:23:4: warning: unknown annotation @autumn:model, did you mean @Autumn:Model?",
+		"models/model.go:26:4: warning: unknown annotation @Autumn:Modle, did you mean @Autumn:Model?",
+		"models/model.go:29:4: warning: unknown annotation @Autumn:Thing",
+		"models/model.go:40:4: warning: @Autumn:Field is only supported on struct fields",
+		"models/model.go:44:20: warning: unknown @Autumn:Field argument \"model\", expected one of: ref",
+	}
+	var actual []string
+	for _, diagnostic := range diagnostics {
+		actual = append(actual, diagnostic.String())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected diagnostics:\n%s\nfound:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestNewEngineCache(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"models/model.go":  &fstest.MapFile{Data: []byte(modelGoFile)},
+		"models/typo.go":   &fstest.MapFile{Data: []byte("package models\n\n// @Autumn:Modle\ntype Typo struct{}\n")},
+		"models/helper.go": &fstest.MapFile{Data: []byte("package models\n\nfunc helper() {}\n")},
+	}
+	opts := Options{CacheDir: t.TempDir()}
+
+	discover := func() (Engine, []ModelTarget) {
+		eng, err := NewEngineWithOptions(rootFS, opts)
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+		modelTargets, err := eng.IdentifyModelTargets()
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+		return eng, modelTargets
+	}
+
+	parsedEngine, parsed := discover()
+	cache := readCache(opts.CacheDir)
+	if len(cache.Files) != 3 {
+		t.Error("expected the 3 files to be cached, found: ", len(cache.Files))
+	} else if entry := cache.Files["models/helper.go"]; len(entry.Models) != 0 || len(entry.Diagnostics) != 0 {
+		t.Errorf("expected the unannotated file to be cached without models, found: %+v", entry)
+	}
+
+	cachedEngine, cached := discover()
+	if len(cached) != 1 || len(parsed) != 1 {
+		t.Fatalf("expected 1 model to be found, found %d and then %d", len(parsed), len(cached))
+	}
+
+	if cached[0].(*modelTarget).typeNode != nil {
+		t.Error("expected the cached model's syntax tree not to be parsed until needed")
+	}
+	if parsedDiagnostics, cachedDiagnostics := parsedEngine.Diagnostics(), cachedEngine.Diagnostics(); !reflect.DeepEqual(parsedDiagnostics, cachedDiagnostics) {
+		t.Errorf("expected cached diagnostics %v, found %v", parsedDiagnostics, cachedDiagnostics)
+	}
+	if !reflect.DeepEqual(parsed[0].ToTemplateVariables(), cached[0].ToTemplateVariables()) {
+		t.Errorf("expected cached model %v, found %v", parsed[0].ToTemplateVariables(), cached[0].ToTemplateVariables())
+	}
+	if cached[0].(*modelTarget).typeNode != nil || len(cached[0].(*modelTarget).resolver.packages) != 0 {
+		t.Error("expected the cached model's fields not to be parsed")
+	}
+	parsedLoc, _ := parsed[0].GetLocation()
+	if cachedLoc, _ := cached[0].GetLocation(); parsedLoc != cachedLoc {
+		t.Errorf("expected cached location %s, found %s", parsedLoc, cachedLoc)
+	}
+	if model, err := cached[0].GetModel(); err != nil {
+		t.Error("unexpected err: ", err)
+	} else if structType, ok := model.(*ast.StructType); !ok || len(structType.Fields.List) != 3 {
+		t.Error("expected the cached model's struct to be parsed, found: ", model)
+	}
+
+	// Changing a file invalidates its entry.
+	rootFS["models/model.go"] = &fstest.MapFile{
+		Data: []byte("package models\n\n// @Autumn:Model\ntype Changed struct{}\n"),
+	}
+	_, changed := discover()
+	if name, _ := changed[0].Name(); len(changed) != 1 || name != "Changed" {
+		t.Error("expected the changed model to be rediscovered, found: ", name)
+	}
+}
+
+func TestNewEngineCacheFields(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module example.com/proj\n")},
+		"base/base.go": &fstest.MapFile{Data: []byte(`package base
+
+type Model struct {
+	ID string
+}
+`)},
+		"users/user.go": &fstest.MapFile{Data: []byte(`package users
+
+import b "example.com/proj/base"
+
+// @Autumn:Model
+type User struct {
+	b.Model
+	Name string
+}
+`)},
+		"orders/order.go": &fstest.MapFile{Data: []byte(`package orders
+
+import "example.com/proj/users"
+
+// @Autumn:Model
+type Order struct {
+	Owner *users.User
+}
+`)},
+	}
+	opts := Options{CacheDir: t.TempDir()}
+
+	discover := func() []ModelTarget {
+		eng, err := NewEngineWithOptions(rootFS, opts)
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+		modelTargets, err := eng.IdentifyModelTargets()
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+		return modelTargets
+	}
+	fieldsOf := func(target ModelTarget) []string {
+		fields, err := target.Fields()
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+		var names []string
+		for _, field := range fields {
+			names = append(names, field.Name+" "+field.Type)
+		}
+		return names
+	}
+
+	discover()
+	cached := discover()
+	if len(cached) != 2 {
+		t.Fatal("expected 2 models, found: ", len(cached))
+	}
+
+	// Relationships are found from the cached fields too.
+	relationships, err := cached[0].Relationships()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if len(relationships) != 1 || relationships[0].To != "example.com/proj/users.User" {
+		t.Error("expected Order to refer to User, found: ", relationships)
+	}
+	for _, target := range cached {
+		if mt := target.(*modelTarget); mt.file != nil || len(mt.resolver.packages) != 0 {
+			t.Errorf("expected the fields and relationships of %s to be cached", mt.name)
+		}
+	}
+	if expected := []string{"ID string", "Name string"}; !reflect.DeepEqual(fieldsOf(cached[1]), expected) {
+		t.Errorf("expected cached fields %v, found %v", expected, fieldsOf(cached[1]))
+	}
+
+	// Changing a package that the fields were resolved from invalidates
+	// them, even though the model's own file is unchanged.
+	rootFS["base/base.go"] = &fstest.MapFile{Data: []byte(`package base
+
+type Model struct {
+	ID      string
+	Version int
+}
+`)}
+	changed := discover()
+	if expected := []string{"ID string", "Version int", "Name string"}; !reflect.DeepEqual(fieldsOf(changed[1]), expected) {
+		t.Errorf("expected changed fields %v, found %v", expected, fieldsOf(changed[1]))
+	}
+}
+
+// benchmarkFS returns a project of the given number of files, one in ten of
+// which define models.
+func benchmarkFS(files int) fstest.MapFS {
+	var rootFS = make(fstest.MapFS)
+	for i := 0; i < files; i++ {
+		var src strings.Builder
+		fmt.Fprintf(&src, "package pkg%d\n\n", i%20)
+		for j := 0; j < 20; j++ {
+			if i%10 == 0 && j == 0 {
+				src.WriteString("// @Autumn:Model\n")
+			}
+			fmt.Fprintf(&src, "type T%d_%d struct {\n\tID string\n\tName string\n}\n\n", i, j)
+			fmt.Fprintf(&src, "func (t *T%d_%d) String() string {\n\treturn t.ID + t.Name\n}\n\n", i, j)
+		}
+		rootFS[fmt.Sprintf("pkg%d/file%d.go", i%20, i)] = &fstest.MapFile{Data: []byte(src.String())}
+	}
+	return rootFS
+}
+
+// BenchmarkIdentifyTargets benchmarks what planning does to identify models:
+// discovery, the diagnostics that are reported and resolving the fields of
+// every model for its templates.
+func BenchmarkIdentifyTargets(b *testing.B) {
+	rootFS := benchmarkFS(500)
+
+	// The baseline is how discovery used to work, parsing every file one
+	// after the other.
+	b.Run("baseline", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := fs.WalkDir(rootFS, ".", func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				contents, err := fs.ReadFile(rootFS, path)
+				if err != nil {
+					return err
+				}
+				targets, _ := modelTargetFromText(path, string(contents))
+				for _, target := range targets {
+					if _, err := target.Fields(); err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				b.Fatal("unexpected err: ", err)
+			}
+		}
+	})
+
+	var benchmarks = []struct {
+		name string
+		opts Options
+	}{
+		{"prefilter", Options{Workers: 1}},
+		{"parallel", Options{}},
+		{"cached", Options{CacheDir: b.TempDir()}},
+	}
+	for _, bench := range benchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				eng, err := NewEngineWithOptions(rootFS, bench.opts)
+				if err != nil {
+					b.Fatal("unexpected err: ", err)
+				}
+				targets, err := eng.IdentifyModelTargets()
+				if err != nil {
+					b.Fatal("unexpected err: ", err)
+				}
+				if diagnostics := eng.Diagnostics(); HasErrors(diagnostics) {
+					b.Fatal("unexpected errors: ", diagnostics)
+				}
+				for _, target := range targets {
+					if _, err := target.Fields(); err != nil {
+						b.Fatal("unexpected err: ", err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestModelTargetFields(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module example.com/proj\n")},
+		"base/base.go": &fstest.MapFile{Data: []byte(`package base
+
+import "time"
+
+type BaseModel struct {
+	ID        string
+	CreatedAt time.Time
+	Meta      *Meta
+	Version   int
+	Audit
+}
+
+type Audit struct {
+	UpdatedBy string
+	Version   int
+}
+
+type Meta struct{}
+`)},
+		"models/common.go": &fstest.MapFile{Data: []byte(`package models
+
+type Timestamps struct {
+	UpdatedAt string
+	Version   int
+}
+`)},
+		"models/user.go": &fstest.MapFile{Data: []byte(`package models
+
+import (
+	"example.com/proj/base"
+	"github.com/other/ext"
+)
+
+// @Autumn:Model
+type User struct {
+	*base.BaseModel
+	Timestamps
+	ext.Thing
+	ID   int
+	Name string ` + "`json:\"name\"`" + `
+}
+`)},
+	}
+
+	eng, err := NewEngine(rootFS)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	modelTargets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if len(modelTargets) != 1 {
+		t.Fatal("expected 1 model, found: ", len(modelTargets))
+	}
+
+	fields, err := modelTargets[0].Fields()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	// ID is shadowed by the model's own ID, and Version is ambiguous.
+	var expected = []Field{
+		{Name: "CreatedAt", Type: "time.Time", EmbeddedFrom: "base.BaseModel"},
+		{Name: "Meta", Type: "*base.Meta", EmbeddedFrom: "base.BaseModel"},
+		{Name: "UpdatedBy", Type: "string", EmbeddedFrom: "base.Audit"},
+		{Name: "UpdatedAt", Type: "string", EmbeddedFrom: "Timestamps"},
+		{Name: "Thing", Type: "ext.Thing", Embedded: true},
+		{Name: "ID", Type: "int"},
+		{Name: "Name", Type: "string", Tag: `json:"name"`},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected fields:\n%+v\nfound:\n%+v", expected, fields)
+	}
+}
+
+func TestModelTargetFieldsImportAlias(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module example.com/proj\n")},
+		"base/base.go": &fstest.MapFile{Data: []byte(`package base
+
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusInactive Status = "inactive"
+)
+
+type Model struct {
+	Status Status
+	Audit
+}
+
+type Audit struct {
+	UpdatedBy string
+}
+`)},
+		"models/user.go": &fstest.MapFile{Data: []byte(`package models
+
+import b "example.com/proj/base"
+
+// @Autumn:Model
+type User struct {
+	*b.Model
+	Name string
+}
+`)},
+	}
+
+	eng, err := NewEngine(rootFS)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	modelTargets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if len(modelTargets) != 1 {
+		t.Fatal("expected 1 model, found: ", len(modelTargets))
+	}
+
+	fields, err := modelTargets[0].Fields()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	// Promoted types are qualified as the model's file imports them.
+	var expected = []Field{
+		{
+			Name:         "Status",
+			Type:         "b.Status",
+			EmbeddedFrom: "b.Model",
+			Enum: &Enum{
+				Name:       "b.Status",
+				Underlying: "string",
+				Values: []EnumValue{
+					{Name: "StatusActive", Value: "active", Literal: `"active"`},
+					{Name: "StatusInactive", Value: "inactive", Literal: `"inactive"`},
+				},
+			},
+		},
+		{Name: "UpdatedBy", Type: "string", EmbeddedFrom: "b.Audit"},
+		{Name: "Name", Type: "string"},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected fields:\n%+v\nfound:\n%+v", expected, fields)
+	}
+}
+
+func TestEngineRelationships(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module example.com/proj\n")},
+		"models/models.go": &fstest.MapFile{Data: []byte(`package models
+
+// @Autumn:Model
+type User struct {
+	ID      string
+	Profile *Profile
+	Orders  []Order
+	Groups  []*Group
+	Parent  *User
+}
+
+// @Autumn:Model
+type Profile struct {
+	User *User
+}
+
+// @Autumn:Model
+type Order struct {
+	UserID   string // @Autumn:Field(ref=User)
+	CouponID string // @Autumn:Field(ref=Coupon)
+}
+
+// @Autumn:Model
+type Group struct {
+	Users []User
+}
+
+// @Autumn:Model
+type Author struct {
+	Books []Book
+}
+
+// @Autumn:Model
+type Book struct {
+	Publisher *Publisher
+}
+
+// @Autumn:Model
+type Publisher struct {
+	Authors []Author
+}
+`)},
+	}
+
+	var reported []Diagnostic
+	eng, err := NewEngineWithOptions(rootFS, Options{
+		Report: func(diagnostic Diagnostic) {
+			reported = append(reported, diagnostic)
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	modelTargets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	// Problems with relationships are only reported with them, as finding
+	// them needs the fields of every model.
+	if diagnostics := eng.Diagnostics(); len(diagnostics) != 0 {
+		t.Errorf("expected no discovery diagnostics, found: %v", diagnostics)
+	} else if eng.(*engine).relationships.graph != nil {
+		t.Error("expected the relationships not to be built for diagnostics")
+	}
+	for _, target := range modelTargets {
+		if _, ok := target.ToTemplateVariables()["Relationships"]; ok {
+			t.Error("expected template variables without relationships")
+		}
+	}
+	if eng.(*engine).relationships.graph != nil || len(reported) != 0 {
+		t.Error("expected the relationships not to be built for template variables")
+	}
+
+	var user ModelTarget
+	for _, target := range modelTargets {
+		if name, _ := target.Name(); name == "User" {
+			user = target
+		}
+	}
+
+	const prefix = "example.com/proj/models."
+	relationships, err := user.Relationships()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	var expected = []Relationship{
+		{Kind: ManyToMany, From: prefix + "User", To: prefix + "Group", ToName: "Group", Field: "Groups", InverseField: "Users"},
+		{Kind: OneToMany, From: prefix + "User", To: prefix + "Order", ToName: "Order", Field: "Orders", InverseField: "UserID"},
+		{Kind: OneToOne, From: prefix + "User", To: prefix + "Profile", ToName: "Profile", Field: "Profile", InverseField: "User"},
+		{Kind: ManyToOne, From: prefix + "User", To: prefix + "User", ToName: "User", Field: "Parent"},
+	}
+	if !reflect.DeepEqual(relationships, expected) {
+		t.Errorf("expected relationships:\n%+v\nfound:\n%+v", expected, relationships)
+	}
+
+	graph, err := eng.Relationships()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	if orders := graph.Of(prefix + "Order"); len(orders) != 1 || orders[0].Kind != ManyToOne || orders[0].Field != "UserID" {
+		t.Errorf("expected Order to have a many-to-one relationship with User, found: %+v", orders)
+	}
+	expectedCycles := [][]string{{prefix + "Author", prefix + "Book", prefix + "Publisher"}}
+	if !reflect.DeepEqual(graph.Cycles, expectedCycles) {
+		t.Errorf("expected cycles %v, found %v", expectedCycles, graph.Cycles)
+	}
+
+	var diagnostics []string
+	for _, diagnostic := range graph.Diagnostics {
+		diagnostics = append(diagnostics, diagnostic.String())
+	}
+	var expectedDiagnostics = []string{
+		"models/models.go:18:6: warning: field CouponID of Order refers to unknown model Coupon",
+		"models/models.go:29:6: warning: models refer to each other in a cycle: " +
+			prefix + "Author -> " + prefix + "Book -> " + prefix + "Publisher -> " + prefix + "Author",
+	}
+	if !reflect.DeepEqual(diagnostics, expectedDiagnostics) {
+		t.Errorf("expected diagnostics:\n%s\nfound:\n%s", strings.Join(expectedDiagnostics, "\n"), strings.Join(diagnostics, "\n"))
+	}
+	if !reflect.DeepEqual(reported, graph.Diagnostics) {
+		t.Errorf("expected the graph's diagnostics to be reported once, found: %v", reported)
+	}
+}
+
+func TestModelTargetFromTextGenerics(t *testing.T) {
+	const text = `package models
+
+// @Autumn:Model
+type Box[T any] struct {
+	Value T
+}
+
+// @Autumn:Model(instantiate="Page[User]")
+// @Autumn:Model(instantiate="Page[Order]", name="OrderPage")
+type Page[T any] struct {
+	Items []T
+	Next  *T
+	Total int
+}
+
+// @Autumn:Model(instantiate="Pair[User]")
+type Pair[K comparable, V any] struct{}
+
+// @Autumn:Model(instantiate="User[int]")
+type User struct{}
+`
+
+	targets, diagnostics := modelTargetFromText("models/model.go", text)
+
+	var expectedDiagnostics = []string{
+		"models/model.go:3:4: error: generic model Box[T any] must be instantiated to generate code for it, e.g. @Autumn:Model(instantiate=\"Box[...]\")",
+		"models/model.go:16:4: error: instantiate=\"Pair[User]\" has 1 type arguments but Pair[K comparable, V any] has 2 type parameters",
+		"models/model.go:19:4: error: instantiate is only supported for generic models, User has no type parameters",
+	}
+	var actualDiagnostics []string
+	for _, diagnostic := range diagnostics {
+		actualDiagnostics = append(actualDiagnostics, diagnostic.String())
+	}
+	if !reflect.DeepEqual(actualDiagnostics, expectedDiagnostics) {
+		t.Errorf("expected diagnostics:\n%s\nfound:\n%s", strings.Join(expectedDiagnostics, "\n"), strings.Join(actualDiagnostics, "\n"))
+	}
+
+	if len(targets) != 2 {
+		t.Fatal("expected 2 instantiated models, found: ", len(targets))
+	}
+
+	page := targets[0]
+	vars := page.ToTemplateVariables()
+	if vars["Name"] != "PageUser" || vars["ModelType"] != "Page[User]" {
+		t.Errorf("expected PageUser to be Page[User], found %v and %v", vars["Name"], vars["ModelType"])
+	}
+	if params := page.TypeParams(); !reflect.DeepEqual(params, []TypeParam{{Name: "T", Constraint: "any"}}) {
+		t.Error("unexpected type params: ", params)
+	}
+	if args := page.TypeArgs(); !reflect.DeepEqual(args, []string{"User"}) {
+		t.Error("unexpected type args: ", args)
+	}
+
+	fields, err := page.Fields()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	var expectedFields = []Field{
+		{Name: "Items", Type: "[]User"},
+		{Name: "Next", Type: "*User"},
+		{Name: "Total", Type: "int"},
+	}
+	if !reflect.DeepEqual(fields, expectedFields) {
+		t.Errorf("expected fields %+v, found %+v", expectedFields, fields)
+	}
+
+	if name, _ := targets[1].Name(); name != "OrderPage" {
+		t.Error("expected the explicitly named instantiation, found: ", name)
+	}
+}
+
+func TestModelTargetEnums(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module example.com/proj\n")},
+		"base/level.go": &fstest.MapFile{Data: []byte(`package base
+
+type Level int
+
+const (
+	LevelLow Level = iota + 1
+	LevelHigh
+)
+`)},
+		"models/status.go": &fstest.MapFile{Data: []byte(`package models
+
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusInactive Status = "inactive"
+	defaultName           = "unrelated"
+)
+
+// Named has no constants, so it isn't an enum.
+type Named string
+`)},
+		"models/user.go": &fstest.MapFile{Data: []byte(`package models
+
+import "example.com/proj/base"
+
+// @Autumn:Model
+type User struct {
+	Status   Status
+	Previous []*Status
+	Level    base.Level
+	Name     Named
+}
+`)},
+	}
+
+	eng, err := NewEngine(rootFS)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	modelTargets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if len(modelTargets) != 1 {
+		t.Fatal("expected 1 model, found: ", len(modelTargets))
+	}
+
+	fields, err := modelTargets[0].Fields()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	status := &Enum{
+		Name:       "Status",
+		Underlying: "string",
+		Values: []EnumValue{
+			{Name: "StatusActive", Value: "active", Literal: `"active"`},
+			{Name: "StatusInactive", Value: "inactive", Literal: `"inactive"`},
+		},
+	}
+	level := &Enum{
+		Name:       "base.Level",
+		Underlying: "int",
+		Values: []EnumValue{
+			{Name: "LevelLow", Value: "1", Literal: "1"},
+			{Name: "LevelHigh", Value: "2", Literal: "2"},
+		},
+	}
+	var expected = []*Enum{status, status, level, nil}
+	for i, field := range fields {
+		if !reflect.DeepEqual(field.Enum, expected[i]) {
+			t.Errorf("expected field %s to have enum %+v, found %+v", field.Name, expected[i], field.Enum)
+		}
+	}
+
+	enums := modelTargets[0].ToTemplateVariables()["Enums"]
+	if !reflect.DeepEqual(enums, []*Enum{status, level}) {
+		t.Errorf("expected the model's enums to be Status and base.Level, found %+v", enums)
+	}
+}
+
+func TestModelTargetGenericEnums(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module example.com/proj\n")},
+		"base/level.go": &fstest.MapFile{Data: []byte(`package base
+
+type Level int
+
+const (
+	LevelLow Level = iota + 1
+	LevelHigh
+)
+`)},
+		"models/filter.go": &fstest.MapFile{Data: []byte(`package models
+
+import "example.com/proj/base"
+
+type Status string
+
+const (
+	StatusActive   Status = "active"
+	StatusInactive Status = "inactive"
+)
+
+// @Autumn:Model(instantiate="Filter[Status]", name="StatusFilter")
+// @Autumn:Model(instantiate="Filter[base.Level]", name="LevelFilter")
+// @Autumn:Model(instantiate="Filter[string]", name="NameFilter")
+type Filter[T any] struct {
+	Value  T
+	Values []*T
+	Limit  int
+}
+`)},
+	}
+
+	eng, err := NewEngine(rootFS)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	modelTargets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if len(modelTargets) != 3 {
+		t.Fatal("expected 3 models, found: ", len(modelTargets))
+	}
+
+	status := &Enum{
+		Name:       "Status",
+		Underlying: "string",
+		Values: []EnumValue{
+			{Name: "StatusActive", Value: "active", Literal: `"active"`},
+			{Name: "StatusInactive", Value: "inactive", Literal: `"inactive"`},
+		},
+	}
+	level := &Enum{
+		Name:       "base.Level",
+		Underlying: "int",
+		Values: []EnumValue{
+			{Name: "LevelLow", Value: "1", Literal: "1"},
+			{Name: "LevelHigh", Value: "2", Literal: "2"},
+		},
+	}
+	var expected = map[string][]*Enum{
+		"StatusFilter": {status, status, nil},
+		"LevelFilter":  {level, level, nil},
+		"NameFilter":   {nil, nil, nil},
+	}
+	for _, target := range modelTargets {
+		name, _ := target.Name()
+		fields, err := target.Fields()
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+
+		for i, field := range fields {
+			if !reflect.DeepEqual(field.Enum, expected[name][i]) {
+				t.Errorf("%s: expected field %s to have enum %+v, found %+v", name, field.Name, expected[name][i], field.Enum)
+			}
+		}
+	}
+}
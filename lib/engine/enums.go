@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"sort"
+)
+
+// Enum is a named type with a basic underlying type, along with the
+// constants of that type declared in its package, e.g.
+//
+//	type Status string
+//
+//	const (
+//		StatusActive   Status = "active"
+//		StatusInactive Status = "inactive"
+//	)
+type Enum struct {
+	// Name is the name of the type as the model's package refers to it,
+	// e.g. `Status` or `base.Status`.
+	Name string
+	// Underlying is the underlying type, e.g. `string` or `int`.
+	Underlying string
+	// Values are the constants of the type, in the order that they're
+	// declared.
+	Values []EnumValue
+}
+
+// EnumValue is a constant of an Enum.
+type EnumValue struct {
+	// Name is the name of the constant, e.g. `StatusActive`.
+	Name string
+	// Value is the value of the constant, e.g. `active` or `1`.
+	Value string
+	// Literal is the value of the constant as a Go literal, e.g. `"active"`
+	// or `1`.
+	Literal string
+}
+
+// enumsOf returns the enums of the fields, once each, in the order that
+// they're first used.
+func enumsOf(fields []Field) []*Enum {
+	var (
+		enums []*Enum
+		seen  = make(map[string]bool)
+	)
+	for _, field := range fields {
+		if field.Enum != nil && !seen[field.Enum.Name] {
+			seen[field.Enum.Name] = true
+			enums = append(enums, field.Enum)
+		}
+	}
+	return enums
+}
+
+// enum returns the enum that the field type, as written in a file of pkg, is,
+// or the element type of a slice or a pointer is, if any. Enum names are
+// qualified with the qualifier, if any, like field types.
+func (r *typeResolver) enum(fieldType ast.Expr, file *ast.File, pkg *packageTypes, qualifier string) *Enum {
+	for {
+		switch t := fieldType.(type) {
+		case *ast.StarExpr:
+			fieldType = t.X
+			continue
+		case *ast.ArrayType:
+			fieldType = t.Elt
+			continue
+		}
+		break
+	}
+
+	decl, ok := r.lookupType(fieldType, file, pkg)
+	if !ok {
+		return nil
+	}
+	underlying, ok := decl.spec.Type.(*ast.Ident)
+	if !ok {
+		return nil
+	} else if basic, ok := types.Universe.Lookup(underlying.Name).(*types.TypeName); !ok {
+		return nil
+	} else if _, ok := basic.Type().(*types.Basic); !ok {
+		return nil
+	}
+
+	values := decl.pkg.enums()[decl.spec.Name.String()]
+	if len(values) == 0 {
+		// Without constants it's just a named type.
+		return nil
+	}
+	return &Enum{
+		Name:       qualifiedType(fieldType, qualifier),
+		Underlying: underlying.Name,
+		Values:     values,
+	}
+}
+
+// enums returns the values of the package's constants, by the name of their
+// type, evaluating them when first needed.
+func (pkg *packageTypes) enums() map[string][]EnumValue {
+	pkg.enumsOnce.Do(func() {
+		pkg.enumValues = make(map[string][]EnumValue)
+		if len(pkg.files) == 0 {
+			return
+		}
+
+		// Type check the package to evaluate its constants, e.g. those
+		// using iota. Imports aren't needed for that, so errors about
+		// them, and anything else, are ignored.
+		conf := types.Config{
+			Error: func(error) {},
+		}
+		checked, _ := conf.Check(pkg.name, pkg.fset, pkg.files, nil)
+		if checked == nil {
+			return
+		}
+
+		var consts []*types.Const
+		scope := checked.Scope()
+		for _, name := range scope.Names() {
+			if c, ok := scope.Lookup(name).(*types.Const); ok {
+				consts = append(consts, c)
+			}
+		}
+		sort.Slice(consts, func(i, j int) bool {
+			return consts[i].Pos() < consts[j].Pos()
+		})
+
+		for _, c := range consts {
+			named, ok := c.Type().(*types.Named)
+			if !ok || named.Obj().Pkg() != checked {
+				continue
+			}
+
+			value := c.Val().ExactString()
+			if c.Val().Kind() == constant.String {
+				value = constant.StringVal(c.Val())
+			}
+			typeName := named.Obj().Name()
+			pkg.enumValues[typeName] = append(pkg.enumValues[typeName], EnumValue{
+				Name:    c.Name(),
+				Value:   value,
+				Literal: c.Val().ExactString(),
+			})
+		}
+	})
+	return pkg.enumValues
+}
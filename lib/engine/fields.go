@@ -0,0 +1,475 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Field is a field of a model, either its own or one promoted from a struct
+// that it embeds.
+type Field struct {
+	Name string
+	// Type is the type of the field as written in the source, qualified
+	// with its package name if the field is promoted from another package,
+	// e.g. `*base.Meta`.
+	Type string
+	// Tag is the field's struct tag, without the backquotes.
+	Tag string
+	// EmbeddedFrom is the embedded struct that the field is promoted from,
+	// as the model's package refers to it (e.g. `BaseModel` or
+	// `base.BaseModel`), or "" for the model's own fields.
+	EmbeddedFrom string
+	// Embedded is whether the field is an embedded type that couldn't be
+	// resolved, e.g. one from outside the project, so its own fields
+	// aren't known.
+	Embedded bool
+	// Ref is the model that the field refers to according to its
+	// `@Autumn:Field(ref=...)` annotation, e.g. `User` or `models.User`.
+	Ref string
+	// Enum is the enum that the field's type (or the element type of a
+	// slice or a pointer) is, if any.
+	Enum *Enum
+}
+
+// typeResolver finds the declarations of the types that models embed, within
+// the project's modules. Packages are parsed when first needed.
+type typeResolver struct {
+	root    fs.FS
+	modules []Module
+	ctxt    *build.Context
+	tests   bool
+	fset    *token.FileSet
+
+	mu       sync.Mutex
+	packages map[string]*packageTypes
+	// hashes are the hashes of packages by directory, see packageHash.
+	hashes map[string]string
+	// lookedUp collects the directories of the packages that are looked
+	// up while it's set, see trackPackages.
+	lookedUp map[string]bool
+}
+
+// packageTypes are the type declarations of a package.
+type packageTypes struct {
+	name  string
+	dir   string
+	fset  *token.FileSet
+	files []*ast.File
+	types map[string]typeDecl
+
+	// enumValues are the values of the package's constants by type,
+	// evaluated when first needed.
+	enumsOnce  sync.Once
+	enumValues map[string][]EnumValue
+}
+
+// typeDecl is a type declaration, along with the file that it's in for
+// resolving its imports.
+type typeDecl struct {
+	spec *ast.TypeSpec
+	file *ast.File
+	pkg  *packageTypes
+}
+
+func newTypeResolver(root fs.FS, modules []Module, ctxt *build.Context, tests bool) *typeResolver {
+	return &typeResolver{
+		root:     root,
+		modules:  modules,
+		ctxt:     ctxt,
+		tests:    tests,
+		fset:     token.NewFileSet(),
+		packages: make(map[string]*packageTypes),
+		hashes:   make(map[string]string),
+	}
+}
+
+// packageIn returns the types declared by the package in dir, which is empty
+// if there isn't one.
+func (r *typeResolver) packageIn(dir string) *packageTypes {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lookedUp != nil {
+		r.lookedUp[dir] = true
+	}
+	if pkg, ok := r.packages[dir]; ok {
+		return pkg
+	}
+
+	pkg := &packageTypes{
+		dir:   dir,
+		fset:  r.fset,
+		types: make(map[string]typeDecl),
+	}
+	r.packages[dir] = pkg
+
+	for _, fileName := range r.packageFiles(dir) {
+		contents, err := fs.ReadFile(r.root, fileName)
+		if err != nil {
+			continue
+		}
+		// Broken files are reported by discovery if they matter, here
+		// they only mean fewer types to resolve.
+		f, err := parser.ParseFile(r.fset, fileName, contents, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+
+		pkg.name = f.Name.String()
+		pkg.files = append(pkg.files, f)
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				pkg.types[typeSpec.Name.String()] = typeDecl{
+					spec: typeSpec,
+					file: f,
+					pkg:  pkg,
+				}
+			}
+		}
+	}
+	return pkg
+}
+
+// packageFiles returns the names of the files of the package in dir, i.e.
+// the Go files in it that the build context matches.
+func (r *typeResolver) packageFiles(dir string) []string {
+	if r.root == nil {
+		return nil
+	}
+	entries, err := fs.ReadDir(r.root, dir)
+	if err != nil {
+		return nil
+	}
+
+	var fileNames []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") ||
+			(strings.HasSuffix(name, "_test.go") && !r.tests) {
+			continue
+		} else if match, err := r.ctxt.MatchFile(dir, name); err != nil || !match {
+			continue
+		}
+		fileNames = append(fileNames, pathpkg.Join(dir, name))
+	}
+	return fileNames
+}
+
+// packageHash returns the hash of the names and contents of the files of the
+// package in dir, which changes whenever the types that it declares might.
+func (r *typeResolver) packageHash(dir string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if hash, ok := r.hashes[dir]; ok {
+		return hash
+	}
+
+	var contents bytes.Buffer
+	for _, fileName := range r.packageFiles(dir) {
+		data, err := fs.ReadFile(r.root, fileName)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&contents, "%s %d\n", fileName, len(data))
+		contents.Write(data)
+	}
+
+	hash := contentHashOf(contents.Bytes())
+	r.hashes[dir] = hash
+	return hash
+}
+
+// trackPackages calls resolve and returns the directories of the packages
+// that were looked up meanwhile, in sorted order, i.e. the packages that what
+// it resolved depends on.
+func (r *typeResolver) trackPackages(resolve func()) []string {
+	r.mu.Lock()
+	r.lookedUp = make(map[string]bool)
+	r.mu.Unlock()
+
+	resolve()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var dirs []string
+	for dir := range r.lookedUp {
+		dirs = append(dirs, dir)
+	}
+	r.lookedUp = nil
+	sort.Strings(dirs)
+	return dirs
+}
+
+// dirOf returns the directory of the package with the given import path, if
+// it's within one of the project's modules.
+func (r *typeResolver) dirOf(importPath string) (string, bool) {
+	var (
+		found string
+		best  = -1
+	)
+	for _, module := range r.modules {
+		if importPath != module.Path && !strings.HasPrefix(importPath, module.Path+"/") {
+			continue
+		} else if len(module.Path) <= best {
+			continue
+		}
+		found = pathpkg.Join(module.Dir, strings.TrimPrefix(importPath, module.Path))
+		best = len(module.Path)
+	}
+	return found, best >= 0
+}
+
+// importedPackage returns the package that the file imports with the given
+// name, if it's within the project.
+func (r *typeResolver) importedPackage(file *ast.File, name string) (*packageTypes, bool) {
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		dir, ok := r.dirOf(importPath)
+		if imp.Name != nil {
+			if imp.Name.String() != name {
+				continue
+			}
+		} else if !ok || r.packageIn(dir).name != name {
+			continue
+		}
+
+		if !ok {
+			return nil, false
+		}
+		return r.packageIn(dir), true
+	}
+	return nil, false
+}
+
+// importName returns the name that the file refers to the package by, e.g.
+// `b` for `import b "example.com/project/base"`, or the package's own name if
+// the file doesn't import it.
+func (r *typeResolver) importName(file *ast.File, pkg *packageTypes) string {
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		} else if dir, ok := r.dirOf(importPath); !ok || dir != pkg.dir {
+			continue
+		}
+
+		if imp.Name == nil {
+			return pkg.name
+		} else if imp.Name.Name == "_" {
+			continue
+		} else if imp.Name.Name == "." {
+			// Dot imports are referred to without a qualifier.
+			return ""
+		}
+		return imp.Name.Name
+	}
+	return pkg.name
+}
+
+// resolve returns the declaration of the embedded type, as written in a
+// file of pkg, if it's a struct type within the project.
+func (r *typeResolver) resolve(expr ast.Expr, file *ast.File, pkg *packageTypes) (typeDecl, bool) {
+	decl, ok := r.lookupType(expr, file, pkg)
+	if !ok {
+		return typeDecl{}, false
+	} else if _, ok := decl.spec.Type.(*ast.StructType); !ok {
+		return typeDecl{}, false
+	}
+	return decl, true
+}
+
+// lookupType returns the declaration of the named type, as written in a file
+// of pkg, if it's declared within the project and isn't an alias.
+func (r *typeResolver) lookupType(expr ast.Expr, file *ast.File, pkg *packageTypes) (typeDecl, bool) {
+	var (
+		name   string
+		target = pkg
+	)
+	switch t := expr.(type) {
+	case *ast.Ident:
+		name = t.Name
+	case *ast.SelectorExpr:
+		pkgIdent, ok := t.X.(*ast.Ident)
+		if !ok {
+			return typeDecl{}, false
+		}
+		if target, ok = r.importedPackage(file, pkgIdent.Name); !ok {
+			return typeDecl{}, false
+		}
+		name = t.Sel.Name
+	default:
+		return typeDecl{}, false
+	}
+
+	decl, ok := target.types[name]
+	if !ok || decl.spec.Assign.IsValid() {
+		return typeDecl{}, false
+	}
+	return decl, true
+}
+
+// fields returns the fields of a struct type declared in a file of pkg,
+// including the fields promoted from the structs that it embeds, in the
+// order that they're declared. As in Go, fields that are promoted from more
+// deeply embedded structs are shadowed by shallower fields of the same name,
+// and fields whose name is ambiguous are left out.
+func (r *typeResolver) fields(structType *ast.StructType, file *ast.File, pkg *packageTypes) []Field {
+	type candidate struct {
+		field Field
+		depth int
+	}
+	var (
+		candidates []candidate
+		visiting   = make(map[*ast.TypeSpec]bool)
+		collect    func(*ast.StructType, *ast.File, *packageTypes, string, int)
+	)
+	modelFile := file
+	collect = func(st *ast.StructType, file *ast.File, declPkg *packageTypes, from string, depth int) {
+		// Fields from other packages are qualified so that they make
+		// sense from the model's file.
+		var qualifier string
+		if declPkg != pkg {
+			qualifier = r.importName(modelFile, declPkg)
+		}
+
+		for _, field := range st.Fields.List {
+			var tag string
+			if field.Tag != nil {
+				tag, _ = strconv.Unquote(field.Tag.Value)
+			}
+
+			ref := fieldAnnotation(field)["ref"]
+			if len(ref) > 0 && len(qualifier) > 0 && !strings.Contains(ref, ".") {
+				ref = qualifier + "." + ref
+			}
+			for _, name := range field.Names {
+				candidates = append(candidates, candidate{
+					field: Field{
+						Name:         name.Name,
+						Type:         qualifiedType(field.Type, qualifier),
+						Tag:          tag,
+						EmbeddedFrom: from,
+						Ref:          ref,
+						Enum:         r.enum(field.Type, file, declPkg, qualifier),
+					},
+					depth: depth,
+				})
+			}
+			if len(field.Names) > 0 {
+				continue
+			}
+
+			embedded := field.Type
+			if star, ok := embedded.(*ast.StarExpr); ok {
+				embedded = star.X
+			}
+			if decl, ok := r.resolve(embedded, file, declPkg); ok && !visiting[decl.spec] {
+				visiting[decl.spec] = true
+				collect(decl.spec.Type.(*ast.StructType), decl.file, decl.pkg, qualifiedType(embedded, qualifier), depth+1)
+				visiting[decl.spec] = false
+				continue
+			}
+
+			candidates = append(candidates, candidate{
+				field: Field{
+					Name:         embeddedName(embedded),
+					Type:         qualifiedType(field.Type, qualifier),
+					Tag:          tag,
+					EmbeddedFrom: from,
+					Embedded:     true,
+					Ref:          ref,
+				},
+				depth: depth,
+			})
+		}
+	}
+	collect(structType, file, pkg, "", 0)
+
+	var (
+		shallowest = make(map[string]int)
+		count      = make(map[string]int)
+	)
+	for _, c := range candidates {
+		if depth, ok := shallowest[c.field.Name]; !ok || c.depth < depth {
+			shallowest[c.field.Name], count[c.field.Name] = c.depth, 1
+		} else if c.depth == depth {
+			count[c.field.Name]++
+		}
+	}
+
+	var fields []Field
+	for _, c := range candidates {
+		if c.depth == shallowest[c.field.Name] && count[c.field.Name] == 1 {
+			fields = append(fields, c.field)
+		}
+	}
+	return fields
+}
+
+// embeddedName returns the name of the field of an embedded type, e.g.
+// `Model` for `base.Model`.
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return types.ExprString(expr)
+}
+
+// qualifiedType returns the type expression as a string, qualifying the
+// types that are declared in its own package with the qualifier, if any.
+func qualifiedType(expr ast.Expr, qualifier string) string {
+	if len(qualifier) == 0 {
+		return types.ExprString(expr)
+	}
+
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if types.Universe.Lookup(t.Name) != nil {
+			return t.Name
+		}
+		return qualifier + "." + t.Name
+	case *ast.StarExpr:
+		return "*" + qualifiedType(t.X, qualifier)
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + qualifiedType(t.Elt, qualifier)
+		}
+		return fmt.Sprintf("[%s]%s", types.ExprString(t.Len), qualifiedType(t.Elt, qualifier))
+	case *ast.MapType:
+		return fmt.Sprintf("map[%s]%s", qualifiedType(t.Key, qualifier), qualifiedType(t.Value, qualifier))
+	case *ast.ChanType:
+		switch t.Dir {
+		case ast.SEND:
+			return "chan<- " + qualifiedType(t.Value, qualifier)
+		case ast.RECV:
+			return "<-chan " + qualifiedType(t.Value, qualifier)
+		}
+		return "chan " + qualifiedType(t.Value, qualifier)
+	case *ast.Ellipsis:
+		return "..." + qualifiedType(t.Elt, qualifier)
+	}
+	return types.ExprString(expr)
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+const (
+	// instantiateArg is the annotation argument that instantiates a
+	// generic model, e.g. `@Autumn:Model(instantiate="Page[User]")`.
+	instantiateArg = "instantiate"
+	// nameArg is the annotation argument that names an instantiated
+	// generic model, e.g. `@Autumn:Model(instantiate="Page[User]",
+	// name="UserPage")`.
+	nameArg = "name"
+)
+
+// TypeParam is a type parameter of a generic model, e.g. `T any`.
+type TypeParam struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
+}
+
+// instantiation is a model instantiated from a generic type, or the type
+// itself if it isn't generic.
+type instantiation struct {
+	name       string
+	typeParams []TypeParam
+	typeArgs   []string
+}
+
+// typeParamsOf returns the type parameters of the type, if it's generic.
+func typeParamsOf(typeSpec *ast.TypeSpec) []TypeParam {
+	if typeSpec.TypeParams == nil {
+		return nil
+	}
+
+	var params []TypeParam
+	for _, field := range typeSpec.TypeParams.List {
+		for _, name := range field.Names {
+			params = append(params, TypeParam{
+				Name:       name.Name,
+				Constraint: types.ExprString(field.Type),
+			})
+		}
+	}
+	return params
+}
+
+// instantiate returns the model that the annotation arguments make of the
+// type. Generic types must be instantiated by the annotation, as code can
+// only be generated for concrete types.
+func instantiate(typeSpec *ast.TypeSpec, args map[string]string) (instantiation, error) {
+	var (
+		typeName     = typeSpec.Name.String()
+		params       = typeParamsOf(typeSpec)
+		instance, ok = args[instantiateArg]
+	)
+	if len(params) == 0 {
+		for _, arg := range []string{instantiateArg, nameArg} {
+			if _, ok := args[arg]; ok {
+				return instantiation{}, fmt.Errorf("%s is only supported for generic models, %s has no type parameters", arg, typeName)
+			}
+		}
+		return instantiation{name: typeName}, nil
+	}
+
+	var declared []string
+	for _, param := range params {
+		declared = append(declared, param.Name+" "+param.Constraint)
+	}
+	if !ok {
+		return instantiation{}, fmt.Errorf(
+			"generic model %s[%s] must be instantiated to generate code for it, e.g. %s(%s=\"%s[...]\")",
+			typeName,
+			strings.Join(declared, ", "),
+			autumnModelIdentifier,
+			instantiateArg,
+			typeName,
+		)
+	}
+
+	expr, err := parser.ParseExpr(instance)
+	var (
+		generic ast.Expr
+		indices []ast.Expr
+	)
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		generic, indices = e.X, []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		generic, indices = e.X, e.Indices
+	}
+	if ident, ok := generic.(*ast.Ident); err != nil || !ok || ident.Name != typeName {
+		return instantiation{}, fmt.Errorf("%s=%q must instantiate %s, e.g. %s[...]", instantiateArg, instance, typeName, typeName)
+	} else if len(indices) != len(params) {
+		return instantiation{}, fmt.Errorf(
+			"%s=%q has %d type arguments but %s[%s] has %d type parameters",
+			instantiateArg,
+			instance,
+			len(indices),
+			typeName,
+			strings.Join(declared, ", "),
+			len(params),
+		)
+	}
+
+	// Models are named after their type and type arguments, e.g.
+	// PageUser for Page[User], unless they're named explicitly.
+	inst := instantiation{
+		name:       typeName,
+		typeParams: params,
+	}
+	named := true
+	for _, index := range indices {
+		inst.typeArgs = append(inst.typeArgs, types.ExprString(index))
+		argName, ok := typeArgName(index)
+		inst.name += argName
+		named = named && ok
+	}
+
+	if name, ok := args[nameArg]; ok {
+		if !token.IsIdentifier(name) {
+			return instantiation{}, fmt.Errorf("%s=%q must be a Go identifier", nameArg, name)
+		}
+		inst.name = name
+	} else if !named {
+		return instantiation{}, fmt.Errorf(
+			"can't name the model instantiated by %s=%q, name it with %s=\"...\"",
+			instantiateArg,
+			instance,
+			nameArg,
+		)
+	}
+	return inst, nil
+}
+
+// typeArgName returns the name of a type argument to name instantiated models
+// with, e.g. `User` for `*models.User`, if it has one.
+func typeArgName(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return strings.ToUpper(e.Name[:1]) + e.Name[1:], true
+	case *ast.SelectorExpr:
+		return e.Sel.Name, true
+	case *ast.StarExpr:
+		return typeArgName(e.X)
+	case *ast.ArrayType:
+		name, ok := typeArgName(e.Elt)
+		return name + "List", ok
+	}
+	return "", false
+}
+
+// substituteTypeArgs returns the type with the type parameters replaced by
+// their type arguments, e.g. `[]User` for `[]T`.
+func substituteTypeArgs(fieldType string, params []TypeParam, args []string) string {
+	expr, err := parser.ParseExpr(fieldType)
+	if err != nil {
+		return fieldType
+	}
+
+	var substitutes = make(map[string]string)
+	for i, param := range params {
+		substitutes[param.Name] = args[i]
+	}
+
+	ast.Inspect(expr, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.SelectorExpr:
+			// Qualified types are never type parameters.
+			return false
+		case *ast.Ident:
+			if substitute, ok := substitutes[n.Name]; ok {
+				n.Name = substitute
+			}
+		}
+		return true
+	})
+	return types.ExprString(expr)
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"fmt"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Module is a Go module within the engine root.
+type Module struct {
+	// Dir is the directory of the module, relative to the engine root, e.g.
+	// `.` or `services/users`.
+	Dir string
+	// Path is the module path, from the module's go.mod.
+	Path string
+}
+
+// DiscoverModules returns the modules of the project at root. If the root has
+// a go.work file these are the modules that the workspace uses, otherwise it
+// is the module of the root's go.mod, if any. The workspace flag is set when
+// a go.work file was found.
+//
+// Modules that the workspace uses from outside of the root (e.g.
+// `use ../shared`) can't be read through root, so they're left out with a
+// warning diagnostic rather than failing.
+func DiscoverModules(root fs.FS) (modules []Module, workspace bool, diagnostics []Diagnostic, err error) {
+	workData, err := fs.ReadFile(root, "go.work")
+	if os.IsNotExist(err) {
+		module, ok, err := readModule(root, ".")
+		if err != nil || !ok {
+			return nil, false, nil, err
+		}
+		return []Module{module}, false, nil, nil
+	} else if err != nil {
+		return nil, false, nil, err
+	}
+
+	workFile, err := modfile.ParseWork("go.work", workData, nil)
+	if err != nil {
+		return nil, true, nil, err
+	}
+
+	for _, use := range workFile.Use {
+		dir := path.Clean(filepath.ToSlash(use.Path))
+		if !fs.ValidPath(dir) {
+			diagnostics = append(diagnostics, newDiagnostic(
+				SeverityWarning,
+				token.Position{Filename: "go.work", Line: use.Syntax.Start.Line, Column: use.Syntax.Start.LineRune},
+				fmt.Sprintf("module %s is outside of the project root, its models aren't discovered", use.Path),
+			))
+			continue
+		}
+
+		module, ok, err := readModule(root, dir)
+		if err != nil {
+			return nil, true, nil, err
+		} else if ok {
+			modules = append(modules, module)
+		}
+	}
+	return modules, true, diagnostics, nil
+}
+
+// readModule reads the module whose go.mod is in the given directory.
+func readModule(root fs.FS, dir string) (Module, bool, error) {
+	modPath := path.Join(dir, "go.mod")
+	data, err := fs.ReadFile(root, modPath)
+	if os.IsNotExist(err) {
+		return Module{}, false, nil
+	} else if err != nil {
+		return Module{}, false, err
+	}
+
+	modFile, err := modfile.ParseLax(modPath, data, nil)
+	if err != nil {
+		return Module{}, false, err
+	} else if modFile.Module == nil {
+		return Module{}, false, nil
+	}
+
+	return Module{
+		Dir:  dir,
+		Path: modFile.Module.Mod.Path,
+	}, true, nil
+}
+
+// moduleFor returns the module that contains the file at the given path,
+// i.e. the module with the most specific directory containing it.
+func moduleFor(modules []Module, fileName string) (Module, bool) {
+	var (
+		found Module
+		best  = -1
+		dir   = path.Dir(fileName)
+	)
+	for _, module := range modules {
+		specificity := len(module.Dir)
+		if module.Dir == "." {
+			specificity = 0
+		} else if dir != module.Dir && !strings.HasPrefix(dir, module.Dir+"/") {
+			continue
+		}
+
+		if specificity > best {
+			found, best = module, specificity
+		}
+	}
+	return found, best >= 0
+}
+
+// importPath returns the import path of the package in the given directory
+// of the module.
+func (m Module) importPath(dir string) string {
+	if m.Dir == "." {
+		if dir == "." {
+			return m.Path
+		}
+		return path.Join(m.Path, dir)
+	}
+	return path.Join(m.Path, strings.TrimPrefix(strings.TrimPrefix(dir, m.Dir), "/"))
+}
@@ -0,0 +1,462 @@
+package engine
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RelationshipKind is the cardinality of a relationship between two models,
+// from the point of view of the model that the relationship is from.
+type RelationshipKind string
+
+const (
+	// OneToOne relationships are between single fields referring to each
+	// other, e.g. `User.Profile` and `Profile.User`.
+	OneToOne RelationshipKind = "one-to-one"
+	// OneToMany relationships are from a model that refers to many of
+	// another, e.g. `User.Orders []Order`.
+	OneToMany RelationshipKind = "one-to-many"
+	// ManyToOne relationships are from a model that many of can refer to the
+	// same other model, e.g. `Order.User *User`.
+	ManyToOne RelationshipKind = "many-to-one"
+	// ManyToMany relationships are between models that refer to many of each
+	// other, e.g. `User.Groups []Group` and `Group.Users []User`.
+	ManyToMany RelationshipKind = "many-to-many"
+)
+
+// inverse returns the kind of the relationship in the other direction.
+func (k RelationshipKind) inverse() RelationshipKind {
+	switch k {
+	case OneToMany:
+		return ManyToOne
+	case ManyToOne:
+		return OneToMany
+	}
+	return k
+}
+
+// Relationship is a relationship from one model to another, found from a
+// field of either model whose type (or the element type of a slice or a
+// pointer) is the other model, or that's annotated with
+// `@Autumn:Field(ref=...)`.
+type Relationship struct {
+	Kind RelationshipKind
+	// From and To are the related models, identified by their import path
+	// (or package name outside of modules) and name, e.g.
+	// `example.com/project/models.User`.
+	From string
+	To   string
+	// ToName is the name of the To model, e.g. `User`.
+	ToName string
+	// Field is the field of From that refers to To, or "" if only To refers
+	// to From.
+	Field string
+	// InverseField is the field of To that refers back to From, if any.
+	InverseField string
+}
+
+// RelationshipGraph is every relationship between a project's models.
+type RelationshipGraph struct {
+	// Relationships are in both directions, ordered by From, To and then
+	// Field.
+	Relationships []Relationship
+	// Cycles are the models, by key, that refer to each other in a cycle,
+	// e.g. A referring to B, B to C and C back to A. Relationships between
+	// two models that refer back to each other, and models that refer to
+	// themselves (e.g. trees), aren't cycles.
+	Cycles [][]string
+	// Diagnostics are the problems found with relationships, such as
+	// fields referring to unknown models and cycles, ordered by file and
+	// position.
+	Diagnostics []Diagnostic
+}
+
+// Of returns the relationships from the model with the given key.
+func (g *RelationshipGraph) Of(key string) []Relationship {
+	var relationships []Relationship
+	for _, relationship := range g.Relationships {
+		if relationship.From == key {
+			relationships = append(relationships, relationship)
+		}
+	}
+	return relationships
+}
+
+// relationships lazily builds the relationship graph of models, as it needs
+// every model's fields.
+type relationships struct {
+	targets  []ModelTarget
+	resolver *typeResolver
+	report   func(Diagnostic)
+
+	once  sync.Once
+	graph *RelationshipGraph
+	err   error
+}
+
+func (r *relationships) build() (*RelationshipGraph, error) {
+	r.once.Do(func() {
+		r.graph, r.err = buildRelationshipGraph(r.targets, r.resolver)
+		if r.err == nil && r.report != nil {
+			for _, diagnostic := range r.graph.Diagnostics {
+				r.report(diagnostic)
+			}
+		}
+	})
+	return r.graph, r.err
+}
+
+func (mt *modelTarget) dir() string {
+	return pathpkg.Dir(mt.definitionPosition.Filename)
+}
+
+// importedDir returns the directory of the project package that the model's
+// file imports with the given name. Models whose fields were cached aren't
+// parsed for it, the packages that their fields refer to were cached along
+// with them, see importedDirs.
+func (mt *modelTarget) importedDir(resolver *typeResolver, name string) (string, bool) {
+	if mt.file == nil {
+		dir, ok := mt.imports[name]
+		return dir, ok
+	}
+	pkg, ok := resolver.importedPackage(mt.file, name)
+	if !ok {
+		return "", false
+	}
+	return pkg.dir, true
+}
+
+// importedDirs returns the directories of the project packages that the
+// fields refer to models in, by the name that the model's file imports them
+// with, for caching along with the fields.
+func (mt *modelTarget) importedDirs(resolver *typeResolver, fields []Field) map[string]string {
+	dirs := make(map[string]string)
+	for _, field := range fields {
+		name, _ := referencedType(field.Type)
+		for _, ref := range []string{name, field.Ref} {
+			idx := strings.LastIndex(ref, ".")
+			if idx < 0 {
+				continue
+			}
+			if dir, ok := mt.importedDir(resolver, ref[:idx]); ok {
+				dirs[ref[:idx]] = dir
+			}
+		}
+	}
+	return dirs
+}
+
+// reference is a field of a model that refers to another model.
+type reference struct {
+	from, to *modelTarget
+	field    string
+	many     bool
+	paired   bool
+}
+
+func buildRelationshipGraph(targets []ModelTarget, resolver *typeResolver) (*RelationshipGraph, error) {
+	if resolver == nil {
+		resolver = newTypeResolver(nil, nil, nil, false)
+	}
+
+	var (
+		byDir       = make(map[string]*modelTarget)
+		byName      = make(map[string][]*modelTarget)
+		diagnostics []Diagnostic
+	)
+	for _, target := range targets {
+		mt := target.(*modelTarget)
+		byDir[mt.dir()+"."+mt.name] = mt
+		byName[mt.name] = append(byName[mt.name], mt)
+	}
+
+	// lookup finds the model with the given name, as the model from would
+	// refer to it, e.g. `User` or `models.User`.
+	lookup := func(from *modelTarget, name string, anywhere bool) (*modelTarget, bool) {
+		dir := from.dir()
+		if idx := strings.LastIndex(name, "."); idx >= 0 {
+			qualifier := name[:idx]
+			if importedDir, ok := from.importedDir(resolver, qualifier); ok {
+				dir = importedDir
+			} else if qualifier != from.pkgName {
+				return nil, false
+			}
+			name = name[idx+1:]
+		}
+
+		if mt, ok := byDir[dir+"."+name]; ok {
+			return mt, true
+		} else if candidates := byName[name]; anywhere && len(candidates) == 1 {
+			return candidates[0], true
+		}
+		return nil, false
+	}
+
+	var refs []*reference
+	for _, target := range targets {
+		mt := target.(*modelTarget)
+		fields, err := mt.Fields()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range fields {
+			name, many := referencedType(field.Type)
+			if len(field.Ref) > 0 {
+				to, ok := lookup(mt, field.Ref, true)
+				if !ok {
+					diagnostics = append(diagnostics, newDiagnostic(
+						SeverityWarning,
+						mt.definitionPosition,
+						fmt.Sprintf("field %s of %s refers to unknown model %s", field.Name, mt.name, field.Ref),
+					))
+					continue
+				}
+				refs = append(refs, &reference{from: mt, to: to, field: field.Name, many: many})
+			} else if len(name) > 0 && !field.Embedded {
+				if to, ok := lookup(mt, name, false); ok {
+					refs = append(refs, &reference{from: mt, to: to, field: field.Name, many: many})
+				}
+			}
+		}
+	}
+
+	// Pair up references between the same models in opposite directions,
+	// as they're the two sides of a single relationship.
+	var (
+		graph = &RelationshipGraph{}
+		edges = make(map[*modelTarget][]*modelTarget)
+	)
+	for i, ref := range refs {
+		if ref.paired {
+			continue
+		}
+
+		if ref.from == ref.to {
+			graph.Relationships = append(graph.Relationships, Relationship{
+				Kind:   relationshipKind(ref.many, false, false),
+				From:   ref.from.Key(),
+				To:     ref.to.Key(),
+				ToName: ref.to.name,
+				Field:  ref.field,
+			})
+			continue
+		}
+
+		var inverse *reference
+		for _, other := range refs[i+1:] {
+			if !other.paired && other.from == ref.to && other.to == ref.from {
+				inverse = other
+				break
+			}
+		}
+
+		kind := relationshipKind(ref.many, inverse != nil, inverse != nil && inverse.many)
+		relationship := Relationship{
+			Kind:   kind,
+			From:   ref.from.Key(),
+			To:     ref.to.Key(),
+			ToName: ref.to.name,
+			Field:  ref.field,
+		}
+		inverseRelationship := Relationship{
+			Kind:         kind.inverse(),
+			From:         ref.to.Key(),
+			To:           ref.from.Key(),
+			ToName:       ref.from.name,
+			InverseField: ref.field,
+		}
+		if inverse != nil {
+			inverse.paired = true
+			relationship.InverseField = inverse.field
+			inverseRelationship.Field = inverse.field
+		}
+		graph.Relationships = append(graph.Relationships, relationship, inverseRelationship)
+	}
+
+	for _, ref := range refs {
+		if ref.from != ref.to {
+			edges[ref.from] = append(edges[ref.from], ref.to)
+		}
+	}
+
+	sort.SliceStable(graph.Relationships, func(i, j int) bool {
+		a, b := graph.Relationships[i], graph.Relationships[j]
+		if a.From != b.From {
+			return a.From < b.From
+		} else if a.To != b.To {
+			return a.To < b.To
+		} else if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.InverseField < b.InverseField
+	})
+
+	for _, cycle := range findCycles(targets, edges) {
+		var keys []string
+		for _, mt := range cycle {
+			keys = append(keys, mt.Key())
+		}
+		graph.Cycles = append(graph.Cycles, keys)
+		diagnostics = append(diagnostics, newDiagnostic(
+			SeverityWarning,
+			cycle[0].definitionPosition,
+			fmt.Sprintf("models refer to each other in a cycle: %s", strings.Join(append(keys, keys[0]), " -> ")),
+		))
+	}
+
+	sortDiagnostics(diagnostics)
+	graph.Diagnostics = diagnostics
+	return graph, nil
+}
+
+// relationshipKind returns the kind of a relationship from a field, which
+// refers to many models or one, given the field referring back if any.
+func relationshipKind(many, hasInverse, inverseMany bool) RelationshipKind {
+	switch {
+	case many && inverseMany:
+		return ManyToMany
+	case many:
+		return OneToMany
+	case hasInverse && !inverseMany:
+		return OneToOne
+	}
+	return ManyToOne
+}
+
+// referencedType returns the name of the type that a field of the given type
+// refers to, e.g. `User` for `[]*User`, and whether it refers to many of
+// them. It returns "" for types that can't refer to a model, such as maps.
+func referencedType(fieldType string) (string, bool) {
+	expr, err := parser.ParseExpr(fieldType)
+	if err != nil {
+		return "", false
+	}
+
+	many := false
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+			continue
+		case *ast.ArrayType:
+			if many {
+				// Slices of slices aren't relationships.
+				return "", false
+			}
+			expr, many = t.Elt, true
+			continue
+		case *ast.Ident, *ast.SelectorExpr:
+			return types.ExprString(t), many
+		}
+		return "", false
+	}
+}
+
+// findCycles returns a cycle of models for each group of models that refer to
+// each other in a cycle, i.e. each strongly connected component of the
+// graph, ordered by their first model.
+func findCycles(targets []ModelTarget, edges map[*modelTarget][]*modelTarget) [][]*modelTarget {
+	// Tarjan's algorithm.
+	var (
+		index      = make(map[*modelTarget]int)
+		lowLink    = make(map[*modelTarget]int)
+		onStack    = make(map[*modelTarget]bool)
+		stack      []*modelTarget
+		components [][]*modelTarget
+		connect    func(*modelTarget)
+	)
+	connect = func(mt *modelTarget) {
+		index[mt], lowLink[mt] = len(index), len(index)
+		stack = append(stack, mt)
+		onStack[mt] = true
+
+		for _, to := range edges[mt] {
+			if _, ok := index[to]; !ok {
+				connect(to)
+				if lowLink[to] < lowLink[mt] {
+					lowLink[mt] = lowLink[to]
+				}
+			} else if onStack[to] && index[to] < lowLink[mt] {
+				lowLink[mt] = index[to]
+			}
+		}
+
+		if lowLink[mt] != index[mt] {
+			return
+		}
+		var component = make(map[*modelTarget]bool)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component[top] = true
+			if top == mt {
+				break
+			}
+		}
+		// Two models can only refer to each other in a cycle through
+		// the two sides of a relationship.
+		if len(component) < 3 {
+			return
+		}
+		if cycle := cycleWithin(targets, component, edges); cycle != nil {
+			components = append(components, cycle)
+		}
+	}
+
+	for _, target := range targets {
+		if _, ok := index[target.(*modelTarget)]; !ok {
+			connect(target.(*modelTarget))
+		}
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i][0].Key() < components[j][0].Key()
+	})
+	return components
+}
+
+// cycleWithin returns a cycle of at least three models through the
+// component, starting from the first model in the order of targets that's
+// part of one, or nil if there's none, e.g. when the component is a chain of
+// models referring back to each other.
+func cycleWithin(targets []ModelTarget, component map[*modelTarget]bool, edges map[*modelTarget][]*modelTarget) []*modelTarget {
+	var (
+		start   *modelTarget
+		path    []*modelTarget
+		visited map[*modelTarget]bool
+		walk    func(*modelTarget) bool
+	)
+	walk = func(mt *modelTarget) bool {
+		path = append(path, mt)
+		visited[mt] = true
+		for _, to := range edges[mt] {
+			if to == start && len(path) >= 3 {
+				return true
+			} else if component[to] && !visited[to] && walk(to) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		visited[mt] = false
+		return false
+	}
+
+	for _, target := range targets {
+		if start = target.(*modelTarget); !component[start] {
+			continue
+		}
+		path, visited = nil, make(map[*modelTarget]bool)
+		if walk(start) {
+			return path
+		}
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package retriever
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ttacon/autumn/lib/config"
+)
+
+// templateExtension is the file extension that framework templates use. The
+// name of a template is its file name without the extension, e.g.
+// `CreateTemplate.tmpl` provides the `CreateTemplate` template.
+const templateExtension = ".tmpl"
+
+// partialsDir is the directory, within a framework, that holds its partials.
+const partialsDir = "partials"
+
+// Override is a template or partial of a framework that the project replaces
+// with its own version from the framework's overrides directory (see
+// OverridesDir).
+type Override struct {
+	Framework string
+	Name      string
+	Partial   bool
+	// Stale is set when the framework doesn't have a template (or partial)
+	// with the override's name, e.g. because it was renamed or removed
+	// upstream, so the override doesn't replace anything.
+	Stale bool
+}
+
+// LoadFrameworkSource loads the templates of each of the given frameworks
+// from their retrieved location under the project root. Frameworks that
+// aren't configured (i.e. have no name) are skipped.
+//
+// Templates and partials found in the project's overrides directory for a
+// framework shadow the framework's templates and partials of the same name;
+// every override that was applied is returned, ordered by framework and then
+// name.
+func LoadFrameworkSource(
+	root fs.FS,
+	frameworks ...config.FrameworkGetter,
+) (config.FrameworkSource, []Override, error) {
+	var (
+		source       = config.NewFrameworkSource()
+		allOverrides []Override
+	)
+
+	for _, frmwrk := range frameworks {
+		name := frmwrk.GetFramework()
+		if len(name) == 0 {
+			continue
+		}
+
+		framework, err := loadFramework(root, FrameworkDir(name))
+		if err == ErrFrameworkNotRetrieved {
+			return nil, nil, fmt.Errorf("%w: %s", err, name)
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		overrides, err := applyOverrides(root, name, framework)
+		if err != nil {
+			return nil, nil, err
+		}
+		allOverrides = append(allOverrides, overrides...)
+
+		source.AddFramework(name, framework)
+	}
+
+	return source, allOverrides, nil
+}
+
+// applyOverrides replaces the templates and partials of the named framework
+// with those from the project's overrides directory.
+func applyOverrides(
+	root fs.FS,
+	name string,
+	framework config.Framework,
+) ([]Override, error) {
+	dir := OverridesDir(name)
+
+	templates, err := readTemplates(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	partials, err := readTemplates(root, path.Join(dir, partialsDir))
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides []Override
+	for _, templateName := range sortedNames(templates) {
+		_, exists := framework.GetTemplate(templateName)
+		overrides = append(overrides, Override{
+			Framework: name,
+			Name:      templateName,
+			Stale:     !exists,
+		})
+		framework.AddTemplate(templateName, templates[templateName])
+	}
+
+	existingPartials := framework.GetPartials()
+	for _, partialName := range sortedNames(partials) {
+		_, exists := existingPartials[partialName]
+		overrides = append(overrides, Override{
+			Framework: name,
+			Name:      partialName,
+			Partial:   true,
+			Stale:     !exists,
+		})
+		framework.AddPartial(partialName, partials[partialName])
+	}
+
+	return overrides, nil
+}
+
+func sortedNames(templates map[string][]byte) []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadFramework loads all templates found at the top level of the given
+// framework directory, its partials, and the helpers declared in its
+// manifest.
+func loadFramework(root fs.FS, dir string) (config.Framework, error) {
+	if _, err := fs.Stat(root, dir); os.IsNotExist(err) {
+		return nil, ErrFrameworkNotRetrieved
+	} else if err != nil {
+		return nil, err
+	}
+
+	framework := config.NewFramework()
+
+	manifest, err := loadManifest(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	for name, helper := range manifest.Helpers {
+		framework.AddHelper(name, []byte(helper))
+	}
+
+	templates, err := readTemplates(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	for name, data := range templates {
+		framework.AddTemplate(name, data)
+	}
+
+	partials, err := readTemplates(root, path.Join(dir, partialsDir))
+	if err != nil {
+		return nil, err
+	}
+	for name, data := range partials {
+		framework.AddPartial(name, data)
+	}
+
+	return framework, nil
+}
+
+// readTemplates reads every template file at the top level of dir, keyed by
+// template name. A missing directory has no templates.
+func readTemplates(root fs.FS, dir string) (map[string][]byte, error) {
+	entries, err := fs.ReadDir(root, dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var templates = make(map[string][]byte)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), templateExtension) {
+			continue
+		}
+
+		data, err := fs.ReadFile(root, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		templates[strings.TrimSuffix(entry.Name(), templateExtension)] = data
+	}
+	return templates, nil
+}
+
+// loadManifest loads the manifest of the framework in the given directory,
+// a framework without a manifest has an empty one.
+func loadManifest(root fs.FS, dir string) (config.FrameworkManifest, error) {
+	var manifest config.FrameworkManifest
+
+	data, err := fs.ReadFile(root, path.Join(dir, config.FrameworkManifestFileName))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	} else if err != nil {
+		return manifest, err
+	}
+
+	if _, err := toml.Decode(string(data), &manifest); err != nil {
+		return manifest, fmt.Errorf("framework manifest %s is malformed: %w", dir, err)
+	}
+	return manifest, nil
+}
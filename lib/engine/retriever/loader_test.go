@@ -0,0 +1,89 @@
+package retriever
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ttacon/autumn/lib/config"
+)
+
+func TestLoadFrameworkSource(t *testing.T) {
+	var rootFS = fstest.MapFS{
+		".autumn/frameworks/example.com__svc/CreateTemplate.tmpl": &fstest.MapFile{
+			Data: []byte(`create`),
+		},
+		".autumn/frameworks/example.com__svc/README.md": &fstest.MapFile{
+			Data: []byte(`not a template`),
+		},
+		".autumn/frameworks/example.com__svc/autumn.toml": &fstest.MapFile{
+			Data: []byte(`[Helpers]
+routePath = "/{{ . }}"
+`),
+		},
+		".autumn/frameworks/example.com__svc/partials/wrapErr.tmpl": &fstest.MapFile{
+			Data: []byte(`upstream wrap`),
+		},
+		".autumn/frameworks/example.com__svc/partials/logErr.tmpl": &fstest.MapFile{
+			Data: []byte(`upstream log`),
+		},
+		".autumn/overrides/example.com__svc/partials/wrapErr.tmpl": &fstest.MapFile{
+			Data: []byte(`project wrap`),
+		},
+		".autumn/overrides/example.com__svc/CreateTemplate.tmpl": &fstest.MapFile{
+			Data: []byte(`project create`),
+		},
+		".autumn/overrides/example.com__svc/RemovedTemplate.tmpl": &fstest.MapFile{
+			Data: []byte(`project removed`),
+		},
+	}
+
+	source, overrides, err := LoadFrameworkSource(rootFS, config.ServiceConfig{
+		FrameworkInfo: config.FrameworkInfo{Module: "example.com/svc"},
+	}, config.RouterConfig{})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	framework, ok := source.GetFramework("example.com/svc")
+	if !ok {
+		t.Fatal("expected framework to be loaded")
+	}
+
+	if data, ok := framework.GetTemplate("CreateTemplate"); !ok || string(data) != "project create" {
+		t.Errorf("unexpected CreateTemplate: %q", data)
+	}
+	if _, ok := framework.GetTemplate("README"); ok {
+		t.Error("expected non-template files to be ignored")
+	}
+	if helper := framework.GetHelpers()["routePath"]; string(helper) != "/{{ . }}" {
+		t.Errorf("unexpected routePath helper: %q", helper)
+	}
+
+	partials := framework.GetPartials()
+	if string(partials["wrapErr"]) != "project wrap" {
+		t.Errorf("expected wrapErr to be overridden, found: %q", partials["wrapErr"])
+	}
+	if string(partials["logErr"]) != "upstream log" {
+		t.Errorf("expected logErr to be kept, found: %q", partials["logErr"])
+	}
+
+	expectedOverrides := []Override{
+		{Framework: "example.com/svc", Name: "CreateTemplate"},
+		{Framework: "example.com/svc", Name: "RemovedTemplate", Stale: true},
+		{Framework: "example.com/svc", Name: "wrapErr", Partial: true},
+	}
+	if !reflect.DeepEqual(overrides, expectedOverrides) {
+		t.Errorf("expected overrides %+v, found %+v", expectedOverrides, overrides)
+	}
+}
+
+func TestLoadFrameworkSourceMissingFramework(t *testing.T) {
+	_, _, err := LoadFrameworkSource(fstest.MapFS{}, config.ServiceConfig{
+		FrameworkInfo: config.FrameworkInfo{Module: "example.com/svc"},
+	})
+	if !errors.Is(err, ErrFrameworkNotRetrieved) {
+		t.Error("expected ErrFrameworkNotRetrieved, found: ", err)
+	}
+}
@@ -1,16 +1,26 @@
 package retriever
 
 import (
+	"errors"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 
 	"github.com/ttacon/autumn/lib/config"
 )
 
+var (
+	// ErrFrameworkNotRetrieved is returned when a configured framework
+	// can't be found in the project, i.e. `autumn get` hasn't been run.
+	ErrFrameworkNotRetrieved = errors.New("framework has not been retrieved, run `autumn get`")
+)
+
 type FrameworkRetriever interface {
 	Get(frmwrk config.FrameworkGetter) error
 }
@@ -19,9 +29,9 @@ func NewFrameworkRetriever(
 	c config.Config,
 	roots config.ConfigLoadRoots,
 ) (FrameworkRetriever, error) {
-
-	// NOTE(ttacon): we're returning an error to reserve the ability to do
-	// config validation at a future time.
+	if problems := c.Validate(nil); len(problems) > 0 {
+		return nil, &config.ValidationError{Problems: problems}
+	}
 
 	return &frameworkRetriever{
 		conf: c,
@@ -40,8 +50,6 @@ func (f *frameworkRetriever) Get(frmwrk config.FrameworkGetter) error {
 		return nil
 	}
 
-	slugSafe := strings.ReplaceAll(frameworkURL, "/", "__")
-
 	// This code assumes that the .autumn directory exists.
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -50,15 +58,27 @@ func (f *frameworkRetriever) Get(frmwrk config.FrameworkGetter) error {
 
 	dir := filepath.Join(
 		cwd,
-		".autumn",
-		"frameworks",
-		slugSafe,
+		FrameworkDir(frameworkURL),
 	)
 
+	auth := f.auth(frmwrk.GetProtocol())
 	r, err := git.PlainClone(dir, false, &git.CloneOptions{
-		URL: frmwrk.GetProtocol() + frameworkURL,
+		URL:  frmwrk.GetProtocol() + frameworkURL,
+		Auth: auth,
 	})
-	if err != nil {
+	if err == git.ErrRepositoryAlreadyExists {
+		// Already retrieved, so bring it up to date so that we can
+		// check out a newly locked version.
+		if r, err = git.PlainOpen(dir); err != nil {
+			return err
+		}
+		if err := r.Fetch(&git.FetchOptions{
+			Auth: auth,
+			Tags: git.AllTags,
+		}); err != nil && err != git.NoErrAlreadyUpToDate {
+			return err
+		}
+	} else if err != nil {
 		return err
 	}
 
@@ -84,3 +104,72 @@ func (f *frameworkRetriever) Get(frmwrk config.FrameworkGetter) error {
 
 	return nil
 }
+
+// auth returns the credentials to retrieve frameworks with over the given
+// protocol, or nil if there are none. Credentials are only used over
+// HTTP(S), for other protocols (e.g. ssh) git's own mechanisms apply.
+func (f *frameworkRetriever) auth(protocol string) transport.AuthMethod {
+	token := f.conf.Auth.Token
+	if len(token) == 0 || !strings.HasPrefix(protocol, "http") {
+		return nil
+	}
+
+	// Hosts such as GitHub accept any username alongside a token.
+	username := f.conf.Auth.Username
+	if len(username) == 0 {
+		username = "autumn"
+	}
+
+	return &githttp.BasicAuth{
+		Username: username,
+		Password: token,
+	}
+}
+
+// FrameworkSlug returns the file system safe name that we store the given
+// framework under.
+func FrameworkSlug(framework string) string {
+	return strings.ReplaceAll(framework, "/", "__")
+}
+
+// FrameworkDir returns the directory, relative to the project root, that the
+// given framework is retrieved into.
+func FrameworkDir(framework string) string {
+	return path.Join(".autumn", "frameworks", FrameworkSlug(framework))
+}
+
+// OverridesDir returns the directory, relative to the project root, that
+// holds the project's overrides for the given framework.
+func OverridesDir(framework string) string {
+	return path.Join(".autumn", "overrides", FrameworkSlug(framework))
+}
+
+// ResolveCommits returns the commit that each of the given frameworks is
+// checked out at in the project at projectDir, keyed by framework. Frameworks
+// that aren't configured, or aren't git repositories, are skipped.
+func ResolveCommits(
+	projectDir string,
+	frameworks ...config.FrameworkGetter,
+) (map[string]string, error) {
+	var commits = make(map[string]string)
+	for _, frmwrk := range frameworks {
+		name := frmwrk.GetFramework()
+		if len(name) == 0 {
+			continue
+		}
+
+		r, err := git.PlainOpen(filepath.Join(projectDir, FrameworkDir(name)))
+		if err == git.ErrRepositoryNotExists {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		head, err := r.Head()
+		if err != nil {
+			return nil, err
+		}
+		commits[name] = head.Hash().String()
+	}
+	return commits, nil
+}
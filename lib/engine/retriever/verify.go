@@ -0,0 +1,109 @@
+package retriever
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/ttacon/autumn/lib/config"
+)
+
+// FrameworkStatus describes whether a framework has been retrieved at the
+// version that the config locks it to.
+type FrameworkStatus struct {
+	Framework string
+	// Version is the version that the config locks the framework to, an
+	// empty version isn't locked to anything.
+	Version string
+	// Commit is the commit that the retrieved framework is checked out at.
+	Commit string
+	// Problem describes why the framework isn't usable as is, it is empty
+	// if the framework is present at its locked version.
+	Problem string
+}
+
+// OK reports whether the framework is present at its locked version.
+func (s FrameworkStatus) OK() bool {
+	return len(s.Problem) == 0
+}
+
+// CheckFrameworks checks that each of the given frameworks has been retrieved
+// into the project at projectDir at its locked version, without touching the
+// network or the retrieved frameworks. Frameworks that aren't configured are
+// skipped.
+func CheckFrameworks(
+	projectDir string,
+	frameworks ...config.FrameworkGetter,
+) ([]FrameworkStatus, error) {
+	var statuses []FrameworkStatus
+	for _, frmwrk := range frameworks {
+		name := frmwrk.GetFramework()
+		if len(name) == 0 {
+			continue
+		}
+
+		status := FrameworkStatus{
+			Framework: name,
+			Version:   frmwrk.GetVersion(),
+		}
+
+		r, err := git.PlainOpen(filepath.Join(projectDir, FrameworkDir(name)))
+		if err == git.ErrRepositoryNotExists {
+			status.Problem = "not retrieved"
+			statuses = append(statuses, status)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		head, err := r.Head()
+		if err != nil {
+			return nil, err
+		}
+		status.Commit = head.Hash().String()
+
+		locked, err := lockedCommit(r, status.Version)
+		if err != nil {
+			status.Problem = err.Error()
+		} else if len(locked) > 0 && !strings.HasPrefix(status.Commit, locked) {
+			status.Problem = fmt.Sprintf(
+				"checked out at %s instead of %s",
+				status.Commit,
+				status.Version,
+			)
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// lockedCommit resolves the version that a framework is locked to into the
+// commit (or commit prefix) that it refers to. Unlocked frameworks resolve to
+// an empty commit.
+func lockedCommit(r *git.Repository, version string) (string, error) {
+	switch {
+	case len(version) == 0:
+		return "", nil
+	case !strings.HasPrefix(version, "v"):
+		return strings.ToLower(version), nil
+	}
+
+	ref, err := r.Reference(plumbing.NewTagReferenceName(version), true)
+	if err != nil {
+		return "", fmt.Errorf("version %s not found, it may need to be fetched", version)
+	}
+
+	// Annotated tags point at a tag object rather than at the commit.
+	if tag, err := r.TagObject(ref.Hash()); err == nil {
+		commit, err := tag.Commit()
+		if err != nil {
+			return "", err
+		}
+		return commit.Hash.String(), nil
+	}
+	return ref.Hash().String(), nil
+}
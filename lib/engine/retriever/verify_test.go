@@ -0,0 +1,81 @@
+package retriever
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/ttacon/autumn/lib/config"
+)
+
+func TestCheckFrameworks(t *testing.T) {
+	projectDir := t.TempDir()
+	frameworkDir := filepath.Join(projectDir, FrameworkDir("example.com/svc"))
+
+	r, err := git.PlainInit(frameworkDir, false)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	commit := func(content string) plumbing.Hash {
+		if err := os.WriteFile(filepath.Join(frameworkDir, "CreateTemplate.tmpl"), []byte(content), 0644); err != nil {
+			t.Fatal("unexpected err: ", err)
+		} else if _, err := w.Add("CreateTemplate.tmpl"); err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+		hash, err := w.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "autumn", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+		return hash
+	}
+
+	first := commit("first")
+	head := commit("second")
+	if _, err := r.CreateTag("v1.0.0", first, nil); err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if _, err := r.CreateTag("v1.1.0", head, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "autumn", When: time.Now()},
+		Message: "annotated",
+	}); err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	framework := func(version string) config.FrameworkGetter {
+		return config.FrameworkInfo{Module: "example.com/svc", Version: version}
+	}
+
+	var tests = []struct {
+		name      string
+		framework config.FrameworkGetter
+		ok        bool
+	}{
+		{"unlocked", framework(""), true},
+		{"locked to head", framework(head.String()[:12]), true},
+		{"locked to an annotated tag at head", framework("v1.1.0"), true},
+		{"locked to an older commit", framework(first.String()), false},
+		{"locked to an older tag", framework("v1.0.0"), false},
+		{"locked to an unknown tag", framework("v2.0.0"), false},
+		{"not retrieved", config.FrameworkInfo{Module: "example.com/other"}, false},
+	}
+
+	for _, test := range tests {
+		statuses, err := CheckFrameworks(projectDir, test.framework)
+		if err != nil {
+			t.Errorf("%s: unexpected err: %v", test.name, err)
+		} else if len(statuses) != 1 || statuses[0].OK() != test.ok {
+			t.Errorf("%s: expected ok to be %v, found %+v", test.name, test.ok, statuses)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUnknownTarget is returned when a selector targets a model that
+	// doesn't exist.
+	ErrUnknownTarget = errors.New("unknown target")
+	// ErrAmbiguousTarget is returned when a selector targets a model by a
+	// name that several models share.
+	ErrAmbiguousTarget = errors.New("ambiguous target")
+)
+
+// Selector selects a subset of model targets, e.g. from the command line.
+// An empty selector selects every model target.
+type Selector struct {
+	// Targets are the models to select, by import path qualified name
+	// (`example.com/project/models.User`), or by package qualified name
+	// (`models.User`) or name (`User`) if only one model has it.
+	Targets []string
+	// Packages are the directories, relative to the engine root, whose
+	// models are selected. A trailing `/...` selects subdirectories too,
+	// as with the go tool, e.g. `./models/...`.
+	Packages []string
+	// Excludes are models, in any of the forms of Targets, to leave out of
+	// the selection.
+	Excludes []string
+	// ExcludePackages are directories, in the form of Packages, whose
+	// models are left out of the selection.
+	ExcludePackages []string
+}
+
+// Filter returns the targets that the selector selects, in their original
+// order. It returns an error wrapping ErrUnknownTarget, listing close matches,
+// if a selector targets a model that doesn't exist, or ErrAmbiguousTarget if
+// it targets a model by a name that several models share.
+func (s Selector) Filter(targets []ModelTarget) ([]ModelTarget, error) {
+	var (
+		wanted   = make(map[ModelTarget]bool)
+		excluded = make(map[ModelTarget]bool)
+	)
+	for _, name := range s.Targets {
+		found, err := FindTargets(targets, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range found {
+			wanted[target] = true
+		}
+	}
+	for _, name := range s.Excludes {
+		// Excluding a model that doesn't exist excludes nothing.
+		found, err := FindTargets(targets, name)
+		if errors.Is(err, ErrAmbiguousTarget) {
+			return nil, err
+		}
+		for _, target := range found {
+			excluded[target] = true
+		}
+	}
+
+	var selected []ModelTarget
+	for _, target := range targets {
+		if len(s.Targets) > 0 && !wanted[target] {
+			continue
+		}
+		if len(s.Packages) > 0 && !matchesPackage(target, s.Packages) {
+			continue
+		}
+		if excluded[target] || matchesPackage(target, s.ExcludePackages) {
+			continue
+		}
+		selected = append(selected, target)
+	}
+
+	return selected, nil
+}
+
+// FindTargets returns the targets that the name selects: the target with that
+// import path qualified name (`example.com/project/models.User`), or else the
+// target with that package qualified name (`models.User`) or name (`User`),
+// which must be unique. Targets that aren't within a known module are
+// qualified by their package name instead of their import path.
+//
+// It returns an error wrapping ErrUnknownTarget, listing close matches, if no
+// target has the name, or ErrAmbiguousTarget, listing the targets that share
+// it, if several do.
+func FindTargets(targets []ModelTarget, name string) ([]ModelTarget, error) {
+	var (
+		names []string
+		// Matches by import path, package and bare name, in order of
+		// precedence.
+		tiers [3][]ModelTarget
+	)
+	for _, target := range targets {
+		targetName, err := target.Name()
+		if err != nil {
+			return nil, err
+		}
+
+		forms := []string{
+			target.Key(),
+			target.PkgName() + "." + targetName,
+			targetName,
+		}
+		for i, form := range forms {
+			if form == name {
+				tiers[i] = append(tiers[i], target)
+				break
+			}
+		}
+		names = append(names, forms...)
+	}
+
+	for _, found := range tiers {
+		if len(found) == 0 {
+			continue
+		}
+
+		// Models defined twice share their key, which is reported when
+		// they're planned.
+		var keys []string
+		for _, target := range found {
+			if key := target.Key(); !containsString(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			return nil, fmt.Errorf(
+				"%w %q, it could be any of: %s",
+				ErrAmbiguousTarget,
+				name,
+				strings.Join(keys, ", "),
+			)
+		}
+		return found, nil
+	}
+	return nil, unknownTargetError(name, names)
+}
+
+func matchesPackage(target ModelTarget, patterns []string) bool {
+	dir := path.Dir(target.GetFileName())
+	for _, pattern := range patterns {
+		pattern = path.Clean(strings.TrimPrefix(pattern, "./"))
+		if strings.HasSuffix(pattern, "/...") || pattern == "..." {
+			prefix := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+			if prefix == "" || prefix == "." || dir == prefix || strings.HasPrefix(dir, prefix+"/") {
+				return true
+			}
+		} else if dir == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownTargetError reports an unknown target along with the known targets
+// that are closest to it.
+func unknownTargetError(wanted string, names []string) error {
+	type match struct {
+		name     string
+		distance int
+	}
+
+	var (
+		matches []match
+		seen    = make(map[string]bool)
+		lowered = strings.ToLower(wanted)
+	)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		distance := levenshtein(lowered, strings.ToLower(name))
+		if distance <= len(wanted)/3+1 ||
+			strings.Contains(strings.ToLower(name), lowered) {
+			matches = append(matches, match{name, distance})
+		}
+	}
+
+	if len(matches) == 0 {
+		return fmt.Errorf("%w %q", ErrUnknownTarget, wanted)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var suggestions []string
+	for _, m := range matches {
+		suggestions = append(suggestions, m.name)
+	}
+	return fmt.Errorf(
+		"%w %q, did you mean: %s?",
+		ErrUnknownTarget,
+		wanted,
+		strings.Join(suggestions, ", "),
+	)
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr := make([]int, len(rb)+1)
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func selectorTestTargets(t *testing.T) []ModelTarget {
+	model := func(pkg, name string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte("package " + pkg + "\n\n// @Autumn:Model\ntype " + name + " struct{}\n")}
+	}
+
+	eng, err := NewEngine(fstest.MapFS{
+		"models/user.go":            model("models", "User"),
+		"models/account.go":         model("models", "Account"),
+		"models/billing/invoice.go": model("billing", "Invoice"),
+		"admin/user.go":             model("admin", "Admin"),
+	})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	targets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	return targets
+}
+
+func targetNames(targets []ModelTarget) []string {
+	var names []string
+	for _, target := range targets {
+		name, _ := target.Name()
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSelectorFilter(t *testing.T) {
+	targets := selectorTestTargets(t)
+
+	var tests = []struct {
+		name     string
+		selector Selector
+		expected []string
+	}{
+		{"empty", Selector{}, []string{"Account", "Admin", "Invoice", "User"}},
+		{"targets", Selector{Targets: []string{"User", "billing.Invoice"}}, []string{"Invoice", "User"}},
+		{"package", Selector{Packages: []string{"./models"}}, []string{"Account", "User"}},
+		{"package tree", Selector{Packages: []string{"./models/..."}}, []string{"Account", "Invoice", "User"}},
+		{"everything", Selector{Packages: []string{"./..."}}, []string{"Account", "Admin", "Invoice", "User"}},
+		{
+			"excludes",
+			Selector{Packages: []string{"./models/..."}, Excludes: []string{"Account"}, ExcludePackages: []string{"./models/billing"}},
+			[]string{"User"},
+		},
+		{
+			// Models and packages are matched separately, even when
+			// they share a name.
+			"excludes are models",
+			Selector{Excludes: []string{"models/billing", "admin"}},
+			[]string{"Account", "Admin", "Invoice", "User"},
+		},
+		{
+			"package excludes are packages",
+			Selector{ExcludePackages: []string{"./Admin", "./admin"}},
+			[]string{"Account", "Invoice", "User"},
+		},
+	}
+
+	for _, test := range tests {
+		selected, err := test.selector.Filter(targets)
+		if err != nil {
+			t.Errorf("%s: unexpected err: %v", test.name, err)
+		} else if names := targetNames(selected); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%s: expected %v, found %v", test.name, test.expected, names)
+		}
+	}
+}
+
+func TestSelectorUnknownTarget(t *testing.T) {
+	_, err := Selector{Targets: []string{"Usr"}}.Filter(selectorTestTargets(t))
+	if !errors.Is(err, ErrUnknownTarget) {
+		t.Fatal("expected ErrUnknownTarget, found: ", err)
+	} else if !strings.Contains(err.Error(), "did you mean: User") {
+		t.Error("expected close matches to be suggested, found: ", err)
+	}
+
+	_, err = Selector{Targets: []string{"Completely"}}.Filter(selectorTestTargets(t))
+	if !errors.Is(err, ErrUnknownTarget) || strings.Contains(err.Error(), "did you mean") {
+		t.Error("expected no suggestions, found: ", err)
+	}
+}
+
+func TestSelectorImportPaths(t *testing.T) {
+	model := func(pkg, name string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte("package " + pkg + "\n\n// @Autumn:Model\ntype " + name + " struct{}\n")}
+	}
+
+	eng, err := NewEngine(fstest.MapFS{
+		"go.mod":                 &fstest.MapFile{Data: []byte("module example.com/project\n")},
+		"users/models/user.go":   model("models", "User"),
+		"users/models/order.go":  model("models", "Order"),
+		"accounts/models/acc.go": model("models", "User"),
+	})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	targets, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	var tests = []struct {
+		name     string
+		selector Selector
+		expected []string
+	}{
+		{"import path", Selector{Targets: []string{"example.com/project/users/models.User"}}, []string{"users/models/user.go"}},
+		{"unambiguous name", Selector{Targets: []string{"Order", "models.Order"}}, []string{"users/models/order.go"}},
+		{
+			"exclude by import path",
+			Selector{Excludes: []string{"example.com/project/accounts/models.User"}},
+			[]string{"users/models/order.go", "users/models/user.go"},
+		},
+	}
+
+	for _, test := range tests {
+		selected, err := test.selector.Filter(targets)
+		if err != nil {
+			t.Errorf("%s: unexpected err: %v", test.name, err)
+			continue
+		}
+
+		var files []string
+		for _, target := range selected {
+			files = append(files, target.GetFileName())
+		}
+		sort.Strings(files)
+		if !reflect.DeepEqual(files, test.expected) {
+			t.Errorf("%s: expected %v, found %v", test.name, test.expected, files)
+		}
+	}
+
+	for _, ambiguous := range []string{"User", "models.User"} {
+		_, err := Selector{Targets: []string{ambiguous}}.Filter(targets)
+		if !errors.Is(err, ErrAmbiguousTarget) {
+			t.Errorf("%s: expected ErrAmbiguousTarget, found: %v", ambiguous, err)
+		} else if expected := "example.com/project/accounts/models.User, example.com/project/users/models.User"; !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected the candidates to be listed, found: %v", ambiguous, err)
+		}
+
+		if _, err := (Selector{Excludes: []string{ambiguous}}).Filter(targets); !errors.Is(err, ErrAmbiguousTarget) {
+			t.Errorf("%s: expected excluding to be ambiguous, found: %v", ambiguous, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/ttacon/autumn/lib/engine"
+	"github.com/ttacon/autumn/lib/generator/render"
+)
+
+var (
+	ErrNoSuchFramework = errors.New("no such framework exists")
+	ErrNoSuchTemplate  = render.ErrNoSuchTemplate
+)
+
+// DefaultTemplates are the templates generated for models whose operations
+// aren't selected, one for each of the core operations.
+var DefaultTemplates = []string{
+	"CreateTemplate",
+	"RetrieveTemplate",
+	"UpdateTemplate",
+	"DeleteTemplate",
+	"ListTemplate",
+	// NOTE(ttacon): should we support a SearchTemplate?
+}
+
+// templateGenerator generates a file for a model from the templates of a
+// framework, rendered one after the other. Every Kind is generated the same
+// way, only their frameworks and templates differ.
+type templateGenerator struct {
+	kind                Kind
+	framework           config.Framework
+	templatesToGenerate []string
+}
+
+// NewContentGenerator returns the generator of the given Kind for the named
+// framework, which renders its templates one after the other. If no specific
+// templates are provided, the DefaultTemplates are generated.
+func NewContentGenerator(
+	kind Kind,
+	frameworkName string,
+	frameworkSource config.FrameworkSource,
+	templatesToGenerate []string,
+) (ContentGenerator, error) {
+	framework, exists := frameworkSource.GetFramework(frameworkName)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s framework %s", ErrNoSuchFramework, kind, frameworkName)
+	}
+
+	if templatesToGenerate == nil {
+		templatesToGenerate = DefaultTemplates
+	}
+
+	return &templateGenerator{
+		kind:                kind,
+		framework:           framework,
+		templatesToGenerate: templatesToGenerate,
+	}, nil
+}
+
+func (g *templateGenerator) Templates() []string {
+	return g.templatesToGenerate
+}
+
+func (g *templateGenerator) GenerateContent(m engine.ModelTarget) ([]byte, error) {
+	var buf = bytes.NewBuffer(nil)
+
+	for _, templName := range g.templatesToGenerate {
+		// Relationships need the fields of every model, so they're only
+		// resolved for templates that use them.
+		vars := m.ToTemplateVariables()
+		if render.Mentions(g.framework, templName, "Relationships") {
+			relationships, err := m.Relationships()
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve the relationships of %s: %w", vars["Name"], err)
+			}
+			vars["Relationships"] = relationships
+		}
+
+		if err := render.Execute(buf, g.framework, templName, vars); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,288 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DriftKind is what drifted between a committed plan and the project.
+type DriftKind string
+
+const (
+	DriftModel     DriftKind = "model"
+	DriftConfig    DriftKind = "config"
+	DriftFramework DriftKind = "framework"
+	DriftFile      DriftKind = "file"
+)
+
+// Drift is a single difference between a committed plan and the project.
+type Drift struct {
+	Kind DriftKind
+	// Subject is what drifted, e.g. the model, config key, framework or
+	// file.
+	Subject string
+	// Detail describes how it drifted.
+	Detail string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s %s: %s", d.Kind, d.Subject, d.Detail)
+}
+
+// DetectDrift compares a committed plan with the current plan for the project
+// at root, along with the generated files on disk. It returns every
+// difference, ordered by kind and subject, so that CI can report exactly what
+// needs to be regenerated.
+func DetectDrift(committed, current *Plan, root fs.FS) ([]Drift, error) {
+	var drifts []Drift
+
+	drifts = append(drifts, modelDrift(committed, current)...)
+
+	configDrift, err := configDrift(committed, current)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, configDrift...)
+
+	drifts = append(drifts, frameworkDrift(committed, current)...)
+
+	fileDrift, err := fileDrift(committed, current, root)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, fileDrift...)
+
+	kindOrder := map[DriftKind]int{DriftModel: 0, DriftConfig: 1, DriftFramework: 2, DriftFile: 3}
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].Kind != drifts[j].Kind {
+			return kindOrder[drifts[i].Kind] < kindOrder[drifts[j].Kind]
+		}
+		return drifts[i].Subject < drifts[j].Subject
+	})
+
+	return drifts, nil
+}
+
+func modelDrift(committed, current *Plan) []Drift {
+	var (
+		drifts    []Drift
+		oldModels = modelsByKey(committed)
+		newModels = modelsByKey(current)
+	)
+
+	for key, old := range oldModels {
+		model, ok := newModels[key]
+		switch {
+		case !ok:
+			drifts = append(drifts, Drift{DriftModel, key, "removed"})
+		case locationFile(old.Location) != locationFile(model.Location):
+			// Moving within a file doesn't change what's generated,
+			// only moving to another file is drift.
+			drifts = append(drifts, Drift{
+				DriftModel,
+				key,
+				fmt.Sprintf("moved from %s to %s", locationFile(old.Location), locationFile(model.Location)),
+			})
+		}
+		if ok && !reflect.DeepEqual(old.Operations, model.Operations) {
+			drifts = append(drifts, Drift{
+				DriftModel,
+				key,
+				fmt.Sprintf("operations changed from %v to %v", old.Operations, model.Operations),
+			})
+		}
+	}
+
+	for key, model := range newModels {
+		if _, ok := oldModels[key]; !ok {
+			drifts = append(drifts, Drift{DriftModel, key, "added at " + model.Location})
+		}
+	}
+
+	return drifts
+}
+
+// locationFile returns the file of a model's location, e.g. `models/user.go`
+// for `models/user.go:12:6`.
+func locationFile(location string) string {
+	for i := 0; i < 2; i++ {
+		idx := strings.LastIndex(location, ":")
+		if idx < 0 {
+			break
+		} else if _, err := strconv.Atoi(location[idx+1:]); err != nil {
+			break
+		}
+		location = location[:idx]
+	}
+	return location
+}
+
+func modelsByKey(plan *Plan) map[string]ModelPlan {
+	var models = make(map[string]ModelPlan)
+	for _, model := range plan.Models {
+		models[model.Key()] = model
+	}
+	return models
+}
+
+func configDrift(committed, current *Plan) ([]Drift, error) {
+	oldKeys, err := flattenConfig(committed)
+	if err != nil {
+		return nil, err
+	}
+	newKeys, err := flattenConfig(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for key, old := range oldKeys {
+		if value, ok := newKeys[key]; !ok {
+			drifts = append(drifts, Drift{DriftConfig, key, "removed, was " + old})
+		} else if value != old {
+			drifts = append(drifts, Drift{DriftConfig, key, fmt.Sprintf("changed from %s to %s", old, value)})
+		}
+	}
+	for key, value := range newKeys {
+		if _, ok := oldKeys[key]; !ok {
+			drifts = append(drifts, Drift{DriftConfig, key, "added as " + value})
+		}
+	}
+	return drifts, nil
+}
+
+// flattenConfig flattens the plan's config into dotted keys (e.g.
+// `Service.Version`) mapped to their JSON encoded values. Unset values are
+// skipped so that they don't show up as drift.
+func flattenConfig(plan *Plan) (map[string]string, error) {
+	data, err := json.Marshal(plan.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var keys = make(map[string]string)
+	var flatten func(prefix string, value interface{})
+	flatten = func(prefix string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				if len(prefix) > 0 {
+					key = prefix + "." + key
+				}
+				flatten(key, child)
+			}
+		case nil:
+		case string:
+			if len(v) > 0 {
+				keys[prefix] = fmt.Sprintf("%q", v)
+			}
+		default:
+			encoded, _ := json.Marshal(v)
+			keys[prefix] = string(encoded)
+		}
+	}
+	flatten("", raw)
+
+	return keys, nil
+}
+
+func frameworkDrift(committed, current *Plan) []Drift {
+	var (
+		drifts     []Drift
+		oldCommits = frameworkCommits(committed)
+		newCommits = frameworkCommits(current)
+	)
+	for framework, old := range oldCommits {
+		if commit, ok := newCommits[framework]; ok && commit != old {
+			drifts = append(drifts, Drift{
+				DriftFramework,
+				framework,
+				fmt.Sprintf("commit changed from %s to %s", orUnknown(old), orUnknown(commit)),
+			})
+		}
+	}
+	return drifts
+}
+
+func frameworkCommits(plan *Plan) map[string]string {
+	var commits = make(map[string]string)
+	for _, model := range plan.Models {
+		for _, file := range model.Files {
+			commits[file.Framework] = file.Commit
+		}
+	}
+	return commits
+}
+
+func orUnknown(s string) string {
+	if len(s) == 0 {
+		return "<unknown>"
+	}
+	return s
+}
+
+func fileDrift(committed, current *Plan, root fs.FS) ([]Drift, error) {
+	var (
+		drifts   []Drift
+		oldFiles = filesByPath(committed)
+		newFiles = filesByPath(current)
+	)
+
+	for path := range oldFiles {
+		if _, ok := newFiles[path]; !ok {
+			drifts = append(drifts, Drift{DriftFile, path, "no longer generated"})
+		}
+	}
+
+	for path, file := range newFiles {
+		old, ok := oldFiles[path]
+		switch {
+		case !ok:
+			drifts = append(drifts, Drift{DriftFile, path, "newly generated"})
+		case old.ContentHash != file.ContentHash:
+			drifts = append(drifts, Drift{DriftFile, path, "generated content changed"})
+		}
+
+		action, err := fileAction(root, file)
+		if err != nil {
+			return nil, err
+		}
+		switch action {
+		case ActionCreate:
+			drifts = append(drifts, Drift{DriftFile, path, "missing on disk"})
+		case ActionUpdate:
+			drifts = append(drifts, Drift{DriftFile, path, "out of date on disk"})
+		}
+	}
+
+	return drifts, nil
+}
+
+func filesByPath(plan *Plan) map[string]FilePlan {
+	var files = make(map[string]FilePlan)
+	for _, model := range plan.Models {
+		for _, file := range model.Files {
+			files[file.OutputPath] = file
+		}
+	}
+	return files
+}
+
+// FormatDrift formats drift for the terminal, one difference per line.
+func FormatDrift(drifts []Drift) string {
+	var lines []string
+	for _, drift := range drifts {
+		lines = append(lines, "  "+drift.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetectDrift(t *testing.T) {
+	committed := testSummaryPlan()
+
+	current := testSummaryPlan()
+	current.Config.Service.Version = "v1.3.0"
+	current.Models[0].Location = "models/user.go:6:6"
+	current.Models[1].Location = "models/accounts.go:4:6"
+	current.Models[1].Files[0].ContentHash = contentHash([]byte("new account"))
+	current.Models = current.Models[:2]
+	for i := range current.Models {
+		current.Models[i].Files[0].Commit = "fedcba9876543210"
+	}
+
+	root := fstest.MapFS{
+		"services/user.go":    &fstest.MapFile{Data: []byte("user")},
+		"services/account.go": &fstest.MapFile{Data: []byte("account")},
+	}
+
+	drifts, err := DetectDrift(committed, current, root)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	expected := []Drift{
+		{DriftModel, "models.Account", "moved from models/account.go to models/accounts.go"},
+		{DriftModel, "models.Order", "removed"},
+		{DriftConfig, "Service.Version", `changed from "v1.2.0" to "v1.3.0"`},
+		{DriftFramework, "example.com/service", "commit changed from 0123456789abcdef to fedcba9876543210"},
+		{DriftFile, "services/account.go", "generated content changed"},
+		{DriftFile, "services/account.go", "out of date on disk"},
+		{DriftFile, "services/order.go", "no longer generated"},
+	}
+	if !reflect.DeepEqual(drifts, expected) {
+		t.Errorf("expected:\n%v\nfound:\n%v", FormatDrift(expected), FormatDrift(drifts))
+	}
+
+	// A plan doesn't drift from itself when everything is generated.
+	root["services/order.go"] = &fstest.MapFile{Data: []byte("order")}
+	if drifts, err := DetectDrift(committed, testSummaryPlan(), root); err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if len(drifts) != 0 {
+		t.Errorf("expected no drift, found:\n%s", FormatDrift(drifts))
+	}
+}
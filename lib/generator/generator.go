@@ -1,10 +1,234 @@
 package generator
 
-import "github.com/ttacon/autumn/lib/engine"
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
-// A Plan looks like:
-//too
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/ttacon/autumn/lib/diff"
+	"github.com/ttacon/autumn/lib/engine"
+)
 
+// Generator plans and renders the code generated for models.
 type Generator interface {
-	CreatePlan(model []engine.ModelTarget)
+	// CreatePlan renders every file for the given models, in memory, and
+	// returns the plan describing them.
+	CreatePlan(models []engine.ModelTarget) (*Plan, error)
+	// RenderPlan renders the files described by the plan. It returns
+	// ErrPlanOutOfDate if the rendered files no longer match the plan,
+	// e.g. because a model or a framework changed since planning.
+	RenderPlan(plan *Plan, models []engine.ModelTarget) ([]File, error)
+}
+
+type generator struct {
+	conf    config.Config
+	source  config.FrameworkSource
+	commits map[string]string
+}
+
+// NewGenerator returns a Generator for the given config, rendering templates
+// from the given frameworks. The commits map each framework to the commit it
+// was retrieved at, which is recorded in plans.
+func NewGenerator(
+	conf config.Config,
+	source config.FrameworkSource,
+	commits map[string]string,
+) Generator {
+	return &generator{
+		conf:    conf,
+		source:  source,
+		commits: commits,
+	}
+}
+
+// File is a generated file along with where it should be stored, relative to
+// the project root.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// Kind is the kind of code that a generator produces.
+type Kind string
+
+const (
+	KindService    Kind = "service"
+	KindController Kind = "controller"
+	KindRouter     Kind = "router"
+)
+
+// ContentGenerator generates the content of the file of a Kind for a model,
+// see NewContentGenerator.
+type ContentGenerator interface {
+	GenerateContent(m engine.ModelTarget) ([]byte, error)
+	Templates() []string
+}
+
+// kindConfig describes how to generate one Kind of file for models.
+type kindConfig struct {
+	kind       Kind
+	framework  string
+	version    string
+	modulePath string
+	// templates returns the templates to generate for a model with the
+	// given operations.
+	templates func(operations []string, explicit bool) []string
+}
+
+// kindConfigs returns how to generate each Kind for the given config, in the
+// order that they're generated.
+func kindConfigs(conf config.Config) []kindConfig {
+	return []kindConfig{
+		{
+			kind:       KindService,
+			framework:  conf.Service.GetFramework(),
+			version:    conf.Service.GetVersion(),
+			modulePath: modulePathOrDefault(conf.Service.ModulePath, "services"),
+			templates: func(operations []string, explicit bool) []string {
+				if explicit {
+					return TemplatesForOperations(operations)
+				}
+				return conf.Service.TemplatesToGenerate
+			},
+		},
+		{
+			kind:       KindController,
+			framework:  conf.Controller.GetFramework(),
+			version:    conf.Controller.GetVersion(),
+			modulePath: modulePathOrDefault(conf.Controller.ModulePath, "controllers"),
+			templates: func(operations []string, _ bool) []string {
+				return TemplatesForOperations(operations)
+			},
+		},
+		{
+			kind:       KindRouter,
+			framework:  conf.Router.GetFramework(),
+			version:    conf.Router.GetVersion(),
+			modulePath: modulePathOrDefault(conf.Router.ModulePath, "routers"),
+			templates: func(operations []string, _ bool) []string {
+				return TemplatesForOperations(operations)
+			},
+		},
+	}
+}
+
+func modulePathOrDefault(modulePath, defaultPath string) string {
+	if len(modulePath) == 0 {
+		return defaultPath
+	}
+	return modulePath
+}
+
+// renderedFile is a rendered file along with how it was rendered.
+type renderedFile struct {
+	FilePlan
+	content []byte
+}
+
+// renderModel renders every file for a single model entirely in memory,
+// nothing is written to disk.
+//
+// The operations generated for each model (see ModelOperations) are shared by
+// the service, controller and router so that, e.g., no route is generated
+// for a service method that doesn't exist.
+func (g *generator) renderModel(model engine.ModelTarget, modelConf config.ModelConfig) ([]renderedFile, []string, error) {
+	name, err := model.Name()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Templates are given the model's fields, which are only resolved when
+	// rendering.
+	if _, err := model.Fields(); err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve the fields of %s: %w", name, err)
+	}
+
+	operations, explicit, err := ModelOperations(g.conf, modelConf, model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []renderedFile
+	for _, kc := range kindConfigs(g.conf) {
+		if len(kc.framework) == 0 {
+			continue
+		}
+
+		templates := kc.templates(operations, explicit)
+		if templates != nil && len(templates) == 0 {
+			// Nothing selected for this model.
+			continue
+		}
+
+		gener8r, err := NewContentGenerator(kc.kind, kc.framework, g.source, templates)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		content, err := gener8r.GenerateContent(model)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate %s for %s: %w", kc.kind, name, err)
+		}
+
+		files = append(files, renderedFile{
+			FilePlan: FilePlan{
+				Generator:   kc.kind,
+				Framework:   kc.framework,
+				Commit:      g.commits[kc.framework],
+				Templates:   gener8r.Templates(),
+				OutputPath:  OutputPath(kc.modulePath, name),
+				ContentHash: contentHash(content),
+			},
+			content: content,
+		})
+	}
+
+	return files, operations, nil
+}
+
+// OutputPath returns the path of the file generated for the named model in
+// the given directory.
+func OutputPath(dir, modelName string) string {
+	return path.Join(filepath.ToSlash(dir), strings.ToLower(modelName)+".go")
+}
+
+// Diff returns a unified diff, suitable for `git apply`, of the changes that
+// storing the given files would make to the project at root. It returns nil
+// if every file is already up to date.
+func Diff(root fs.FS, files []File) ([]byte, error) {
+	var patch []byte
+	for _, file := range files {
+		oldName := "a/" + file.Path
+		existing, err := fs.ReadFile(root, file.Path)
+		if os.IsNotExist(err) {
+			oldName = diff.DevNull
+		} else if err != nil {
+			return nil, err
+		}
+
+		patch = append(
+			patch,
+			diff.Unified(oldName, "b/"+file.Path, existing, file.Content)...,
+		)
+	}
+	return patch, nil
+}
+
+// Store writes the given files to disk under the root directory, creating
+// any directories as needed.
+func Store(root string, files []File) error {
+	for _, file := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModeDir|0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, file.Content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,419 @@
+package generator
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/ttacon/autumn/lib/engine"
+)
+
+// Test files
+var (
+	modelsGoFile = `
+package models
+
+// @Autumn:Model
+type ResourceFoo struct {
+    ID string
+}
+
+// @Autumn:Model(operations="Retrieve,List")
+type ReadOnlyFoo struct {
+    ID string
+}
+
+// @Autumn:Model
+type AppendOnlyFoo struct {
+    ID string
+}
+`
+)
+
+// testFramework returns a framework with a template for each of the default
+// operations that renders `<kind>:<Operation><Name>;`.
+func testFramework(kind string) map[string][]byte {
+	templates := make(map[string][]byte)
+	for _, op := range []string{"Create", "Retrieve", "Update", "Delete", "List"} {
+		templates[op+"Template"] = []byte(kind + ":" + op + "{{.Name}};")
+	}
+	return templates
+}
+
+func testModels(t *testing.T) []engine.ModelTarget {
+	eng, err := engine.NewEngine(fstest.MapFS{
+		"models/models.go": &fstest.MapFile{Data: []byte(modelsGoFile)},
+	})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	models, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	return models
+}
+
+func TestGenerateFilesOperations(t *testing.T) {
+	source := config.FrameworkSourceFromMap(map[string]map[string][]byte{
+		"example.com/service":    testFramework("service"),
+		"example.com/controller": testFramework("controller"),
+		"example.com/router":     testFramework("router"),
+	})
+
+	conf := config.Config{
+		Service: config.ServiceConfig{
+			FrameworkInfo:       config.FrameworkInfo{Module: "example.com/service"},
+			TemplatesToGenerate: []string{"CreateTemplate", "RetrieveTemplate", "DeleteTemplate"},
+		},
+		Controller: config.ControllerConfig{
+			FrameworkInfo: config.FrameworkInfo{Module: "example.com/controller"},
+		},
+		Router: config.RouterConfig{
+			FrameworkInfo: config.FrameworkInfo{Module: "example.com/router"},
+			ModulePath:    "api/routes",
+		},
+		Models: map[string]config.ModelConfig{
+			"AppendOnlyFoo": {Operations: []string{"Create", "List"}},
+		},
+	}
+
+	models := testModels(t)
+	gener8r := NewGenerator(conf, source, nil)
+	plan, err := gener8r.CreatePlan(models)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	files, err := gener8r.RenderPlan(plan, models)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	var actual = make(map[string]string)
+	for _, file := range files {
+		actual[file.Path] = string(file.Content)
+	}
+
+	expected := map[string]string{
+		"services/resourcefoo.go":      "service:CreateResourceFoo;service:RetrieveResourceFoo;service:DeleteResourceFoo;",
+		"controllers/resourcefoo.go":   "controller:CreateResourceFoo;controller:RetrieveResourceFoo;controller:DeleteResourceFoo;",
+		"api/routes/resourcefoo.go":    "router:CreateResourceFoo;router:RetrieveResourceFoo;router:DeleteResourceFoo;",
+		"services/readonlyfoo.go":      "service:RetrieveReadOnlyFoo;service:ListReadOnlyFoo;",
+		"controllers/readonlyfoo.go":   "controller:RetrieveReadOnlyFoo;controller:ListReadOnlyFoo;",
+		"api/routes/readonlyfoo.go":    "router:RetrieveReadOnlyFoo;router:ListReadOnlyFoo;",
+		"services/appendonlyfoo.go":    "service:CreateAppendOnlyFoo;service:ListAppendOnlyFoo;",
+		"controllers/appendonlyfoo.go": "controller:CreateAppendOnlyFoo;controller:ListAppendOnlyFoo;",
+		"api/routes/appendonlyfoo.go":  "router:CreateAppendOnlyFoo;router:ListAppendOnlyFoo;",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected files:\n%v\nfound:\n%v", expected, actual)
+	}
+}
+
+func TestCreatePlan(t *testing.T) {
+	source := config.FrameworkSourceFromMap(map[string]map[string][]byte{
+		"example.com/service": testFramework("service"),
+	})
+
+	conf := config.Config{
+		Service: config.ServiceConfig{
+			FrameworkInfo: config.FrameworkInfo{Module: "example.com/service"},
+		},
+	}
+
+	// Models are discovered in no particular order, so pick one by name.
+	var models []engine.ModelTarget
+	for _, model := range testModels(t) {
+		if name, _ := model.Name(); name == "ResourceFoo" {
+			models = append(models, model)
+		}
+	}
+	plan, err := NewGenerator(conf, source, map[string]string{
+		"example.com/service": "0123abcd",
+	}).CreatePlan(models)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	if len(plan.Models) != 1 {
+		t.Fatal("expected a single model plan, found: ", len(plan.Models))
+	}
+
+	modelPlan := plan.Models[0]
+	name, _ := models[0].Name()
+	if modelPlan.Name != name || modelPlan.Package != "models" {
+		t.Errorf("unexpected model plan: %+v", modelPlan)
+	}
+
+	expectedFile := FilePlan{
+		Generator:  KindService,
+		Framework:  "example.com/service",
+		Commit:     "0123abcd",
+		Templates:  []string{"CreateTemplate", "RetrieveTemplate", "UpdateTemplate", "DeleteTemplate", "ListTemplate"},
+		OutputPath: "services/" + strings.ToLower(name) + ".go",
+		ContentHash: contentHash([]byte(
+			"service:Create" + name + ";service:Retrieve" + name +
+				";service:Update" + name + ";service:Delete" + name +
+				";service:List" + name + ";",
+		)),
+	}
+	if len(modelPlan.Files) != 1 || !reflect.DeepEqual(modelPlan.Files[0], expectedFile) {
+		t.Errorf("expected files %+v, found %+v", []FilePlan{expectedFile}, modelPlan.Files)
+	}
+
+	// Changing a framework after planning invalidates the plan.
+	framework, _ := source.GetFramework("example.com/service")
+	framework.AddTemplate("ListTemplate", []byte("changed"))
+	if _, err := NewGenerator(conf, source, nil).RenderPlan(plan, models); !errors.Is(err, ErrPlanOutOfDate) {
+		t.Error("expected ErrPlanOutOfDate, found: ", err)
+	}
+}
+
+func TestOperationsFromTemplates(t *testing.T) {
+	operations, err := OperationsFromTemplates([]string{"CreateTemplate", "ListTemplate"})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if expected := []string{"Create", "List"}; !reflect.DeepEqual(operations, expected) {
+		t.Errorf("expected %v, found %v", expected, operations)
+	}
+
+	for _, templ := range []string{"Template", "Header"} {
+		_, err := OperationsFromTemplates([]string{"CreateTemplate", templ})
+		if !errors.Is(err, ErrNotAnOperation) || !strings.Contains(err.Error(), `"`+templ+`"`) {
+			t.Errorf("expected ErrNotAnOperation naming %q, found: %v", templ, err)
+		}
+	}
+
+	if templates := TemplatesForOperations(operations); !reflect.DeepEqual(
+		templates,
+		[]string{"CreateTemplate", "ListTemplate"},
+	) {
+		t.Error("unexpected templates: ", templates)
+	}
+}
+
+func TestModelOperationsOtherTemplates(t *testing.T) {
+	var model engine.ModelTarget
+	for _, m := range testModels(t) {
+		if name, _ := m.Name(); name == "ResourceFoo" {
+			model = m
+		}
+	}
+
+	conf := config.Config{
+		Service: config.ServiceConfig{
+			FrameworkInfo:       config.FrameworkInfo{Module: "example.com/service"},
+			TemplatesToGenerate: []string{"Header", "CreateTemplate", "ListTemplate"},
+		},
+	}
+
+	// Only the service renders the Header.
+	operations, explicit, err := ModelOperations(conf, config.ModelConfig{}, model)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if expected := []string{"Create", "List"}; explicit || !reflect.DeepEqual(operations, expected) {
+		t.Errorf("expected operations %v, found %v (explicit: %t)", expected, operations, explicit)
+	}
+
+	// A controller would have to generate the Header operation too.
+	conf.Controller.Module = "example.com/controller"
+	if _, _, err := ModelOperations(conf, config.ModelConfig{}, model); !errors.Is(err, ErrNotAnOperation) {
+		t.Error("expected ErrNotAnOperation, found: ", err)
+	}
+}
+
+func TestTemplateGenerator(t *testing.T) {
+	models := testModels(t)
+	source := config.FrameworkSourceFromMap(map[string]map[string][]byte{
+		"go.mongodb.org/mongo-driver/mongo": {
+			"CreateTemplate": []byte(`func Create{{.Name}}(){  }`),
+		},
+	})
+
+	gener8r, err := NewContentGenerator(
+		KindController,
+		"go.mongodb.org/mongo-driver/mongo",
+		source,
+		[]string{"CreateTemplate"},
+	)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	data, err := gener8r.GenerateContent(models[0])
+	if err != nil {
+		t.Error("unexpected err: ", err)
+	} else if expected := `func CreateResourceFoo(){  }`; string(data) != expected {
+		t.Error("received unexpected file content: ", string(data))
+	}
+
+	// Relationships are only resolved for the templates that use them.
+	relationshipsSource := config.FrameworkSourceFromMap(map[string]map[string][]byte{
+		"example.com/relationships": {
+			"CreateTemplate": []byte(`{{ printf "%T" .Relationships }};`),
+		},
+	})
+	gener8r, err = NewContentGenerator(KindController, "example.com/relationships", relationshipsSource, []string{"CreateTemplate"})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	data, err = gener8r.GenerateContent(models[0])
+	if err != nil {
+		t.Error("unexpected err: ", err)
+	} else if expected := `[]engine.Relationship;`; string(data) != expected {
+		t.Error("received unexpected file content: ", string(data))
+	}
+
+	// Missing templates are reported by name.
+	gener8r, err = NewContentGenerator(KindRouter, "go.mongodb.org/mongo-driver/mongo", source, []string{"SearchTemplate"})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	if _, err := gener8r.GenerateContent(models[0]); !errors.Is(err, ErrNoSuchTemplate) ||
+		!strings.Contains(err.Error(), "SearchTemplate") {
+		t.Error("expected ErrNoSuchTemplate naming SearchTemplate, found: ", err)
+	}
+
+	if _, err := NewContentGenerator(KindRouter, "example.com/missing", source, nil); !errors.Is(err, ErrNoSuchFramework) {
+		t.Error("expected ErrNoSuchFramework, found: ", err)
+	}
+}
+
+func TestCreatePlanDuplicateModels(t *testing.T) {
+	eng, err := engine.NewEngine(fstest.MapFS{
+		"go.mod": &fstest.MapFile{
+			Data: []byte("module example.com/project\n"),
+		},
+		"users/models.go": &fstest.MapFile{
+			Data: []byte("package users\n\n// @Autumn:Model\ntype User struct{}\n"),
+		},
+		"accounts/models.go": &fstest.MapFile{
+			Data: []byte("package accounts\n\n// @Autumn:Model\ntype User struct{}\n"),
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	models, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	source := config.FrameworkSourceFromMap(map[string]map[string][]byte{
+		"example.com/service": testFramework("service"),
+	})
+	conf := config.Config{
+		Service: config.ServiceConfig{
+			FrameworkInfo: config.FrameworkInfo{Module: "example.com/service"},
+		},
+	}
+
+	_, err = NewGenerator(conf, source, nil).CreatePlan(models)
+	if !errors.Is(err, ErrDuplicateModel) {
+		t.Fatal("expected ErrDuplicateModel, found: ", err)
+	}
+
+	expected := "services/user.go would be generated for both " +
+		"example.com/project/accounts.User (accounts/models.go:4:6) and " +
+		"example.com/project/users.User (users/models.go:4:6)"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error to contain %q, found: %v", expected, err)
+	}
+}
+
+func TestModelConfigs(t *testing.T) {
+	eng, err := engine.NewEngine(fstest.MapFS{
+		"go.mod": &fstest.MapFile{
+			Data: []byte("module example.com/project\n"),
+		},
+		"users/models.go": &fstest.MapFile{
+			Data: []byte("package users\n\n// @Autumn:Model\ntype User struct{}\n\n// @Autumn:Model\ntype Order struct{}\n"),
+		},
+		"accounts/models.go": &fstest.MapFile{
+			Data: []byte("package accounts\n\n// @Autumn:Model\ntype User struct{}\n"),
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	models, err := eng.IdentifyModelTargets()
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	var tests = []struct {
+		name     string
+		models   map[string]config.ModelConfig
+		expected map[string]config.ModelConfig
+		err      error
+	}{
+		{
+			"import paths",
+			map[string]config.ModelConfig{
+				"example.com/project/users.User":    {Operations: []string{"Create"}},
+				"example.com/project/accounts.User": {Operations: []string{"Retrieve"}},
+			},
+			map[string]config.ModelConfig{
+				"example.com/project/users.User":    {Operations: []string{"Create"}},
+				"example.com/project/accounts.User": {Operations: []string{"Retrieve"}},
+			},
+			nil,
+		},
+		{
+			"unambiguous names",
+			map[string]config.ModelConfig{
+				"Order":         {Operations: []string{"List"}},
+				"accounts.User": {Operations: []string{"Retrieve"}},
+				"Missing":       {Operations: []string{"Create"}},
+			},
+			map[string]config.ModelConfig{
+				"example.com/project/users.Order":   {Operations: []string{"List"}},
+				"example.com/project/accounts.User": {Operations: []string{"Retrieve"}},
+			},
+			nil,
+		},
+		{
+			"most qualified wins",
+			map[string]config.ModelConfig{
+				"Order":                           {Operations: []string{"List"}},
+				"users.Order":                     {Operations: []string{"Retrieve"}},
+				"example.com/project/users.Order": {Operations: []string{"Create"}},
+			},
+			map[string]config.ModelConfig{
+				"example.com/project/users.Order": {Operations: []string{"Create"}},
+			},
+			nil,
+		},
+		{
+			"ambiguous name",
+			map[string]config.ModelConfig{
+				"User": {Operations: []string{"Create"}},
+			},
+			nil,
+			engine.ErrAmbiguousTarget,
+		},
+	}
+
+	for _, test := range tests {
+		configs, err := ModelConfigs(config.Config{Models: test.models}, models)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("%s: expected %v, found: %v", test.name, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected err: %v", test.name, err)
+		} else if !reflect.DeepEqual(configs, test.expected) {
+			t.Errorf("%s: expected %v, found %v", test.name, test.expected, configs)
+		}
+	}
+}
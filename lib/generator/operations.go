@@ -0,0 +1,151 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/ttacon/autumn/lib/engine"
+)
+
+// operationsArg is the annotation argument that selects the operations to
+// generate for a model, e.g. `@Autumn:Model(operations="Retrieve,List")`.
+const operationsArg = "operations"
+
+// templateSuffix is the suffix of the template that generates an operation,
+// i.e. the Create operation is generated by the CreateTemplate template.
+const templateSuffix = "Template"
+
+// ErrNotAnOperation is returned for templates that don't generate an
+// operation.
+var ErrNotAnOperation = errors.New("not an operation template")
+
+// ModelConfigs returns the config of each of the given models, keyed by their
+// Key. Models are configured by their import path qualified name, e.g.
+// `[Models."example.com/project/users.User"]`, or by their package qualified
+// name or their name if only one model has it (see engine.FindTargets), with
+// the most qualified taking precedence. Config for models that aren't among
+// the given models is ignored.
+func ModelConfigs(conf config.Config, models []engine.ModelTarget) (map[string]config.ModelConfig, error) {
+	var names []string
+	for name := range conf.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		configs    = make(map[string]config.ModelConfig)
+		qualifiers = make(map[string]int)
+	)
+	for _, name := range names {
+		found, err := engine.FindTargets(models, name)
+		if errors.Is(err, engine.ErrUnknownTarget) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid Models.%s: %w", name, err)
+		}
+
+		for _, model := range found {
+			key := model.Key()
+
+			// Import path qualified names take precedence over
+			// package qualified names, which take precedence over
+			// names.
+			qualified := 0
+			if key == name {
+				qualified = 2
+			} else if strings.Contains(name, ".") {
+				qualified = 1
+			}
+			if other, ok := qualifiers[key]; ok && other >= qualified {
+				continue
+			}
+			configs[key], qualifiers[key] = conf.Models[name], qualified
+		}
+	}
+	return configs, nil
+}
+
+// ModelOperations returns the operations to generate for the given model and
+// whether they were explicitly selected for it. In order of precedence, the
+// operations are taken from:
+//
+//  1. The model's annotation, `@Autumn:Model(operations="Retrieve,List")`.
+//  2. The model's config, `[Models.<Name>] Operations = ["Retrieve", "List"]`,
+//     as found by ModelConfigs.
+//  3. The service's TemplatesToGenerate, or the service's default templates.
+func ModelOperations(conf config.Config, modelConf config.ModelConfig, model engine.ModelTarget) ([]string, bool, error) {
+	if ops, ok := model.GetAnnotationArgs()[operationsArg]; ok {
+		var operations []string
+		for _, op := range strings.Split(ops, ",") {
+			if op = strings.TrimSpace(op); len(op) > 0 {
+				operations = append(operations, op)
+			}
+		}
+		return operations, true, nil
+	}
+
+	if modelConf.Operations != nil {
+		return modelConf.Operations, true, nil
+	}
+
+	templates := conf.Service.TemplatesToGenerate
+	if templates == nil {
+		templates = DefaultTemplates
+	}
+	if len(conf.Controller.GetFramework()) == 0 && len(conf.Router.GetFramework()) == 0 {
+		// Without a controller or a router, templates that don't
+		// generate an operation (e.g. a Header) are only the service's
+		// concern.
+		var operationTemplates []string
+		for _, templ := range templates {
+			if _, ok := operationOf(templ); ok {
+				operationTemplates = append(operationTemplates, templ)
+			}
+		}
+		templates = operationTemplates
+	}
+	operations, err := OperationsFromTemplates(templates)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid Service.TemplatesToGenerate: %w", err)
+	}
+	return operations, false, nil
+}
+
+// TemplatesForOperations returns the names of the templates that generate the
+// given operations.
+func TemplatesForOperations(operations []string) []string {
+	templates := make([]string, len(operations))
+	for i, op := range operations {
+		templates[i] = op + templateSuffix
+	}
+	return templates
+}
+
+// OperationsFromTemplates returns the operations generated by the given
+// templates. Every template must generate an operation, as the controller and
+// router generate the same operations from their own templates.
+func OperationsFromTemplates(templates []string) ([]string, error) {
+	var operations []string
+	for _, templ := range templates {
+		op, ok := operationOf(templ)
+		if !ok {
+			return nil, fmt.Errorf(
+				"%w: template %q doesn't generate an operation, it must be named <Operation>%s",
+				ErrNotAnOperation,
+				templ,
+				templateSuffix,
+			)
+		}
+		operations = append(operations, op)
+	}
+	return operations, nil
+}
+
+// operationOf returns the operation that the template generates, if any.
+func operationOf(templ string) (string, bool) {
+	op := strings.TrimSuffix(templ, templateSuffix)
+	return op, len(op) > 0 && op != templ
+}
@@ -0,0 +1,309 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ttacon/autumn/lib/config"
+	"github.com/ttacon/autumn/lib/engine"
+)
+
+var (
+	// ErrPlanOutOfDate is returned when the files described by a plan
+	// can no longer be rendered as planned.
+	ErrPlanOutOfDate = errors.New("plan is out of date, re-run `autumn plan`")
+	// ErrDuplicateModel is returned when two models can't be told apart,
+	// or would generate the same file.
+	ErrDuplicateModel = errors.New("duplicate model")
+)
+
+// Plan describes every file that applying will generate, per model. See
+// WritePlan and ReadPlan for how plans are stored.
+type Plan struct {
+	// SchemaVersion is the version of the plan file schema, see
+	// PlanSchemaVersion.
+	SchemaVersion int `json:"schemaVersion"`
+	// Config is the config that the plan was created with.
+	Config PlanConfig  `json:"config"`
+	Models []ModelPlan `json:"models"`
+}
+
+// PlanConfig is the config that a plan was created with, keyed as in the
+// config file. It's separate from config.Config so that the plan file schema
+// only changes along with PlanSchemaVersion, and so that nothing else (e.g.
+// credentials) ends up in plans.
+type PlanConfig struct {
+	Name       string                     `json:"Name"`
+	Controller PlanFrameworkConfig        `json:"Controller"`
+	Router     PlanFrameworkConfig        `json:"Router"`
+	Service    PlanFrameworkConfig        `json:"Service"`
+	Models     map[string]PlanModelConfig `json:"Models"`
+	Discovery  PlanDiscoveryConfig        `json:"Discovery"`
+	Build      PlanBuildConfig            `json:"Build"`
+}
+
+// PlanFrameworkConfig is the config of a generator in a PlanConfig.
+type PlanFrameworkConfig struct {
+	Module              string   `json:"Module"`
+	Version             string   `json:"Version"`
+	Protocol            string   `json:"Protocol"`
+	ModulePath          string   `json:"ModulePath"`
+	TemplatesToGenerate []string `json:"TemplatesToGenerate,omitempty"`
+}
+
+// PlanModelConfig is the config of a single model in a PlanConfig.
+type PlanModelConfig struct {
+	Operations []string `json:"Operations"`
+}
+
+// PlanDiscoveryConfig is the discovery config in a PlanConfig.
+type PlanDiscoveryConfig struct {
+	Include []string `json:"Include"`
+	Exclude []string `json:"Exclude"`
+}
+
+// PlanBuildConfig is the build config in a PlanConfig.
+type PlanBuildConfig struct {
+	GOOS   string   `json:"GOOS"`
+	GOARCH string   `json:"GOARCH"`
+	Tags   []string `json:"Tags"`
+	Tests  bool     `json:"Tests"`
+}
+
+// NewPlanConfig returns the config as recorded in plans.
+func NewPlanConfig(conf config.Config) PlanConfig {
+	framework := func(info config.FrameworkInfo, modulePath string, templates []string) PlanFrameworkConfig {
+		return PlanFrameworkConfig{
+			Module:              info.Module,
+			Version:             info.Version,
+			Protocol:            info.Protocol,
+			ModulePath:          modulePath,
+			TemplatesToGenerate: templates,
+		}
+	}
+
+	planConf := PlanConfig{
+		Name:       conf.Name,
+		Controller: framework(conf.Controller.FrameworkInfo, conf.Controller.ModulePath, nil),
+		Router:     framework(conf.Router.FrameworkInfo, conf.Router.ModulePath, nil),
+		Service:    framework(conf.Service.FrameworkInfo, conf.Service.ModulePath, conf.Service.TemplatesToGenerate),
+		Discovery: PlanDiscoveryConfig{
+			Include: conf.Discovery.Include,
+			Exclude: conf.Discovery.Exclude,
+		},
+		Build: PlanBuildConfig{
+			GOOS:   conf.Build.GOOS,
+			GOARCH: conf.Build.GOARCH,
+			Tags:   conf.Build.Tags,
+			Tests:  conf.Build.Tests,
+		},
+	}
+	if conf.Models != nil {
+		planConf.Models = make(map[string]PlanModelConfig, len(conf.Models))
+		for name, model := range conf.Models {
+			planConf.Models[name] = PlanModelConfig{Operations: model.Operations}
+		}
+	}
+	return planConf
+}
+
+// config returns the generator config that the plan was created with.
+func (c PlanConfig) config() config.Config {
+	framework := func(fc PlanFrameworkConfig) config.FrameworkInfo {
+		return config.FrameworkInfo{
+			Module:   fc.Module,
+			Version:  fc.Version,
+			Protocol: fc.Protocol,
+		}
+	}
+
+	return config.Config{
+		Name: c.Name,
+		Controller: config.ControllerConfig{
+			FrameworkInfo: framework(c.Controller),
+			ModulePath:    c.Controller.ModulePath,
+		},
+		Router: config.RouterConfig{
+			FrameworkInfo: framework(c.Router),
+			ModulePath:    c.Router.ModulePath,
+		},
+		Service: config.ServiceConfig{
+			FrameworkInfo:       framework(c.Service),
+			ModulePath:          c.Service.ModulePath,
+			TemplatesToGenerate: c.Service.TemplatesToGenerate,
+		},
+	}
+}
+
+// ModelPlan describes the files that will be generated for a single model.
+type ModelPlan struct {
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	// ImportPath is the import path of the model's package, empty if it
+	// isn't within a module.
+	ImportPath string `json:"importPath"`
+	// Location is where the model is defined, e.g. `models/user.go:12:6`.
+	Location   string     `json:"location"`
+	Operations []string   `json:"operations"`
+	Files      []FilePlan `json:"files"`
+}
+
+// Key returns the key that identifies the model within the project, see
+// engine.ModelKey.
+func (m ModelPlan) Key() string {
+	return engine.ModelKey(m.ImportPath, m.Package, m.Name)
+}
+
+// FilePlan describes a single file that will be generated.
+type FilePlan struct {
+	// Generator is the kind of generator that renders the file.
+	Generator Kind `json:"generator"`
+	// Framework is the framework whose templates are rendered.
+	Framework string `json:"framework"`
+	// Commit is the commit that the framework was retrieved at.
+	Commit string `json:"commit"`
+	// Templates are the templates that are rendered, in order.
+	Templates []string `json:"templates"`
+	// OutputPath is where the file is written, relative to the project
+	// root.
+	OutputPath string `json:"outputPath"`
+	// ContentHash is the hash of the rendered file.
+	ContentHash string `json:"contentHash"`
+}
+
+func (g *generator) CreatePlan(models []engine.ModelTarget) (*Plan, error) {
+	plan := &Plan{
+		SchemaVersion: PlanSchemaVersion,
+		Config:        NewPlanConfig(g.conf),
+		Models:        make([]ModelPlan, 0, len(models)),
+	}
+
+	modelConfigs, err := ModelConfigs(g.conf, models)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		locations   = make(map[string]string)
+		outputPaths = make(map[string]string)
+		duplicates  []string
+	)
+	for _, model := range models {
+		name, err := model.Name()
+		if err != nil {
+			return nil, err
+		}
+
+		location, err := model.GetLocation()
+		if err != nil {
+			return nil, err
+		}
+
+		modelPlan := ModelPlan{
+			Name:       name,
+			Package:    model.PkgName(),
+			ImportPath: model.ImportPath(),
+			Location:   location,
+		}
+
+		// Keep going after a duplicate so that every one is reported at
+		// once.
+		key := modelPlan.Key()
+		if other, ok := locations[key]; ok {
+			duplicates = append(duplicates, fmt.Sprintf("%s is defined at both %s and %s", key, other, location))
+			continue
+		}
+		locations[key] = location
+
+		files, operations, err := g.renderModel(model, modelConfigs[key])
+		if err != nil {
+			return nil, err
+		}
+
+		modelPlan.Operations = operations
+		modelPlan.Files = make([]FilePlan, len(files))
+		for i, file := range files {
+			if other, ok := outputPaths[file.OutputPath]; ok {
+				duplicates = append(duplicates, fmt.Sprintf(
+					"%s would be generated for both %s and %s",
+					file.OutputPath,
+					other,
+					describeModel(key, location),
+				))
+			}
+			outputPaths[file.OutputPath] = describeModel(key, location)
+			modelPlan.Files[i] = file.FilePlan
+		}
+
+		plan.Models = append(plan.Models, modelPlan)
+	}
+
+	if len(duplicates) > 0 {
+		return nil, fmt.Errorf(
+			"%w, rename one of the models or configure its output:\n  %s",
+			ErrDuplicateModel,
+			strings.Join(duplicates, "\n  "),
+		)
+	}
+
+	return plan, nil
+}
+
+func describeModel(key, location string) string {
+	return fmt.Sprintf("%s (%s)", key, location)
+}
+
+func (g *generator) RenderPlan(plan *Plan, models []engine.ModelTarget) ([]File, error) {
+	modelConfigs, err := ModelConfigs(g.conf, models)
+	if err != nil {
+		return nil, err
+	}
+
+	var byKey = make(map[string]engine.ModelTarget)
+	for _, model := range models {
+		byKey[model.Key()] = model
+	}
+
+	var files []File
+	for _, modelPlan := range plan.Models {
+		model, ok := byKey[modelPlan.Key()]
+		if !ok {
+			return nil, fmt.Errorf("%w: model %s no longer exists", ErrPlanOutOfDate, modelPlan.Key())
+		}
+
+		rendered, _, err := g.renderModel(model, modelConfigs[model.Key()])
+		if err != nil {
+			return nil, err
+		}
+
+		var byPath = make(map[string]renderedFile)
+		for _, file := range rendered {
+			byPath[file.OutputPath] = file
+		}
+		if len(byPath) != len(modelPlan.Files) {
+			return nil, fmt.Errorf("%w: files generated for %s changed", ErrPlanOutOfDate, modelPlan.Key())
+		}
+
+		for _, filePlan := range modelPlan.Files {
+			file, ok := byPath[filePlan.OutputPath]
+			if !ok || file.ContentHash != filePlan.ContentHash {
+				return nil, fmt.Errorf("%w: %s changed", ErrPlanOutOfDate, filePlan.OutputPath)
+			}
+			files = append(files, File{
+				Path:    file.OutputPath,
+				Content: file.content,
+			})
+		}
+	}
+
+	return files, nil
+}
+
+// contentHash returns the hash that plans record for rendered content.
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+// PlanSchemaVersion is the version of the plan file schema that this version
+// of autumn reads and writes. It must be bumped, along with plan.schema.json,
+// whenever the plan types change in a way that older readers would
+// misinterpret, and ReadPlan taught to migrate the previous version if that's
+// possible.
+const PlanSchemaVersion = 1
+
+// PlanJSONSchema is the JSON Schema describing the current plan file schema.
+//
+//go:embed plan.schema.json
+var PlanJSONSchema []byte
+
+var (
+	// ErrUnsupportedPlanVersion is returned when reading a plan file with
+	// another schema version than PlanSchemaVersion.
+	ErrUnsupportedPlanVersion = errors.New("unsupported plan schema version")
+)
+
+// WritePlan writes the plan to w as indented JSON. The output is
+// deterministic: fields are written in a fixed order, and models are sorted
+// by import path, package, name and then location.
+func WritePlan(w io.Writer, plan *Plan) error {
+	plan.SchemaVersion = PlanSchemaVersion
+	sort.SliceStable(plan.Models, func(i, j int) bool {
+		a, b := plan.Models[i], plan.Models[j]
+		if a.ImportPath != b.ImportPath {
+			return a.ImportPath < b.ImportPath
+		} else if a.Package != b.Package {
+			return a.Package < b.Package
+		} else if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Location < b.Location
+	})
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadPlan reads a plan written by WritePlan. Plans with another schema
+// version (including those from newer versions of autumn) result in an
+// ErrUnsupportedPlanVersion error explaining what to do.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("plan is malformed: %w", err)
+	}
+
+	rawVersion, ok := header["schemaVersion"]
+	if !ok {
+		// Plans written before the plan model existed were a map of
+		// model names to models.
+		return nil, fmt.Errorf(
+			"%w: the plan predates versioned plans, re-run `autumn plan`",
+			ErrUnsupportedPlanVersion,
+		)
+	}
+
+	var version int
+	if err := json.Unmarshal(rawVersion, &version); err != nil {
+		return nil, fmt.Errorf("plan is malformed: invalid schemaVersion: %w", err)
+	}
+	switch {
+	case version > PlanSchemaVersion:
+		return nil, fmt.Errorf(
+			"%w: the plan has schema version %d but this version of autumn only supports up to %d, upgrade autumn",
+			ErrUnsupportedPlanVersion,
+			version,
+			PlanSchemaVersion,
+		)
+	case version != PlanSchemaVersion:
+		return nil, fmt.Errorf(
+			"%w: the plan has schema version %d, re-run `autumn plan`",
+			ErrUnsupportedPlanVersion,
+			version,
+		)
+	}
+
+	var plan Plan
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("plan is malformed: %w", err)
+	}
+
+	return &plan, nil
+}
@@ -0,0 +1,155 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func testPlan() *Plan {
+	return &Plan{
+		Models: []ModelPlan{
+			{
+				Name:     "User",
+				Package:  "models",
+				Location: "models/user.go:4:6",
+				Files: []FilePlan{
+					{
+						Generator:   KindService,
+						Framework:   "example.com/service",
+						Templates:   []string{"CreateTemplate"},
+						OutputPath:  "services/user.go",
+						ContentHash: contentHash([]byte("user")),
+					},
+				},
+			},
+			{
+				Name:     "Account",
+				Package:  "models",
+				Location: "models/account.go:4:6",
+			},
+		},
+	}
+}
+
+func TestWriteAndReadPlan(t *testing.T) {
+	var first, second bytes.Buffer
+	if err := WritePlan(&first, testPlan()); err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if err := WritePlan(&second, testPlan()); err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	if first.String() != second.String() {
+		t.Error("expected plans to be written deterministically")
+	}
+	if !strings.HasPrefix(first.String(), fmt.Sprintf("{\n  \"schemaVersion\": %d,\n", PlanSchemaVersion)) {
+		t.Errorf("expected schemaVersion to be written first, found:\n%s", first.String())
+	}
+
+	plan, err := ReadPlan(&first)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	if plan.Models[0].Name != "Account" || plan.Models[1].Name != "User" {
+		t.Error("expected models to be sorted by name")
+	}
+	if expected := testPlan().Models[0].Files; !reflect.DeepEqual(plan.Models[1].Files, expected) {
+		t.Errorf("expected files %+v, found %+v", expected, plan.Models[1].Files)
+	}
+}
+
+func TestReadPlanVersions(t *testing.T) {
+	var tests = []struct {
+		name string
+		plan string
+		err  bool
+	}{
+		{
+			name: "current plan is read",
+			plan: fmt.Sprintf(`{"schemaVersion": %d, "config": {}, "models": [{"name": "User", "package": "models"}]}`, PlanSchemaVersion),
+		},
+		{
+			name: "unversioned plan is rejected",
+			plan: `{"config": {}, "models": [{"name": "User"}]}`,
+			err:  true,
+		},
+		{
+			name: "legacy plan is rejected",
+			plan: `{"User": {"Model": {"Name": "User", "PackageName": "models", "Raw": {}}, "Config": {}}}`,
+			err:  true,
+		},
+		{
+			name: "newer plan is rejected",
+			plan: fmt.Sprintf(`{"schemaVersion": %d, "config": {}, "models": []}`, PlanSchemaVersion+1),
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		plan, err := ReadPlan(strings.NewReader(test.plan))
+		if test.err {
+			if !errors.Is(err, ErrUnsupportedPlanVersion) {
+				t.Errorf("%s: expected ErrUnsupportedPlanVersion, found: %v", test.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected err: %v", test.name, err)
+		} else if plan.SchemaVersion != PlanSchemaVersion {
+			t.Errorf("%s: expected version %d, found %d", test.name, PlanSchemaVersion, plan.SchemaVersion)
+		}
+	}
+}
+
+// TestPlanJSONSchema checks that the JSON schema describes every field that
+// we write, so that it is updated alongside the plan types.
+func TestPlanJSONSchema(t *testing.T) {
+	var schema struct {
+		Required    []string `json:"required"`
+		Definitions map[string]struct {
+			Required []string `json:"required"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(PlanJSONSchema, &schema); err != nil {
+		t.Fatal("schema is not valid JSON: ", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePlan(&buf, testPlan()); err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	var (
+		written map[string]json.RawMessage
+		conf    map[string]json.RawMessage
+		models  []map[string]json.RawMessage
+		files   []map[string]json.RawMessage
+	)
+	json.Unmarshal(buf.Bytes(), &written)
+	json.Unmarshal(written["config"], &conf)
+	json.Unmarshal(written["models"], &models)
+	json.Unmarshal(models[1]["files"], &files)
+
+	checkKeys := func(what string, keys map[string]json.RawMessage, required []string) {
+		var actual []string
+		for key := range keys {
+			actual = append(actual, key)
+		}
+		sort.Strings(actual)
+		sort.Strings(required)
+		if !reflect.DeepEqual(actual, required) {
+			t.Errorf("%s: schema requires %v, plan has %v", what, required, actual)
+		}
+	}
+
+	checkKeys("plan", written, schema.Required)
+	checkKeys("config", conf, schema.Definitions["config"].Required)
+	checkKeys("modelPlan", models[1], schema.Definitions["modelPlan"].Required)
+	checkKeys("filePlan", files[0], schema.Definitions["filePlan"].Required)
+}
@@ -0,0 +1,297 @@
+package render
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FuncMap returns the functions that are available to every framework
+// template. Functions that take more than one argument take the value to
+// operate on last so that they can be used in pipelines, e.g.
+// `{{ .Name | pluralize | kebabCase | printf "/%s" }}`.
+//
+// Naming functions:
+//
+//   - words: splits an identifier into its words, see SplitWords.
+//   - camelCase: `resource_foo` -> `resourceFoo`, `HTTPServer` -> `httpServer`.
+//   - pascalCase: `resource_foo` -> `ResourceFoo`, `user_id` -> `UserID`.
+//   - snakeCase: `ResourceFoo` -> `resource_foo`, `HTTPServer` -> `http_server`.
+//   - kebabCase: `ResourceFoo` -> `resource-foo`.
+//   - lowerFirst: `ResourceFoo` -> `resourceFoo`, nothing else changes.
+//   - upperFirst: `resourceFoo` -> `ResourceFoo`, nothing else changes.
+//   - pluralize: `ResourceCategory` -> `ResourceCategories`, only the last
+//     word of the identifier is changed.
+//   - singularize: `ResourceCategories` -> `ResourceCategory`.
+//
+// String functions:
+//
+//   - lower, upper, trim: as in the strings package.
+//   - trimPrefix PREFIX S, trimSuffix SUFFIX S, replace OLD NEW S.
+//   - contains SUBSTR S, hasPrefix PREFIX S, hasSuffix SUFFIX S.
+//   - repeat COUNT S, quote S.
+//   - split SEP S, join SEP LIST.
+//
+// List functions:
+//
+//   - list ITEMS...: creates a list from its arguments.
+//   - first LIST, last LIST, rest LIST, reverse LIST.
+//   - has ITEM LIST: reports whether LIST contains ITEM.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		// Naming.
+		"words":       SplitWords,
+		"camelCase":   CamelCase,
+		"pascalCase":  PascalCase,
+		"snakeCase":   SnakeCase,
+		"kebabCase":   KebabCase,
+		"lowerFirst":  LowerFirst,
+		"upperFirst":  UpperFirst,
+		"pluralize":   Pluralize,
+		"singularize": Singularize,
+
+		// Strings.
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"quote":      strconv.Quote,
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+
+		// Lists.
+		"list":    func(items ...interface{}) []interface{} { return items },
+		"first":   first,
+		"last":    last,
+		"rest":    rest,
+		"reverse": reverse,
+		"has":     has,
+	}
+}
+
+// SplitWords splits an identifier into its words. Words are separated by
+// any non-alphanumeric character and by changes in case, where a run of
+// upper case letters is treated as a single acronym word:
+//
+//	ResourceFoo -> [Resource Foo]
+//	HTTPServer  -> [HTTP Server]
+//	userID      -> [user ID]
+//	user_id     -> [user id]
+//	userIDs     -> [user IDs]
+//	Model2Field -> [Model2 Field]
+//
+// Digits are kept with the word that precedes them.
+func SplitWords(s string) []string {
+	var (
+		words   []string
+		current []rune
+		runes   = []rune(s)
+	)
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) &&
+				!isPluralSuffix(runes, i+1)
+			// Start a new word on a lower to upper transition
+			// (fooBar) and at the last upper case letter of an
+			// acronym that is followed by a word (HTTPServer).
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
+
+// isPluralSuffix reports whether the rune at i is a lone trailing "s", as in
+// the plural of an acronym (IDs, URLs).
+func isPluralSuffix(runes []rune, i int) bool {
+	return runes[i] == 's' &&
+		(i+1 == len(runes) || !unicode.IsLower(runes[i+1]))
+}
+
+// commonInitialisms are the words that are written entirely in upper case
+// when producing camelCase or PascalCase identifiers, following the Go
+// naming conventions.
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true,
+	"DNS": true, "EOF": true, "GUID": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true,
+	"QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true,
+	"SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true,
+	"XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// PascalCase joins the words of s with each word capitalized, writing common
+// initialisms (ID, HTTP, URL, ...) entirely in upper case, including their
+// plurals (IDs, URLs).
+func PascalCase(s string) string {
+	var b strings.Builder
+	for _, word := range SplitWords(s) {
+		upper := strings.ToUpper(word)
+		if commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		} else if singular := upper[:len(upper)-1]; len(word) > 2 &&
+			strings.HasSuffix(word, "s") && commonInitialisms[singular] {
+			b.WriteString(singular + "s")
+			continue
+		}
+		b.WriteString(UpperFirst(strings.ToLower(word)))
+	}
+	return b.String()
+}
+
+// CamelCase is PascalCase with the first word written entirely in lower
+// case.
+func CamelCase(s string) string {
+	words := SplitWords(s)
+	if len(words) == 0 {
+		return ""
+	}
+	return strings.ToLower(words[0]) + PascalCase(strings.Join(words[1:], " "))
+}
+
+// SnakeCase joins the lower cased words of s with underscores.
+func SnakeCase(s string) string {
+	return joinLower(s, "_")
+}
+
+// KebabCase joins the lower cased words of s with hyphens.
+func KebabCase(s string) string {
+	return joinLower(s, "-")
+}
+
+func joinLower(s, sep string) string {
+	words := SplitWords(s)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, sep)
+}
+
+// LowerFirst lower cases the first letter of s.
+func LowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// UpperFirst upper cases the first letter of s.
+func UpperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func join(sep string, list interface{}) (string, error) {
+	items, err := toSlice(list)
+	if err != nil {
+		return "", err
+	}
+	strs := make([]string, len(items))
+	for i, item := range items {
+		strs[i] = fmt.Sprint(item)
+	}
+	return strings.Join(strs, sep), nil
+}
+
+func first(list interface{}) (interface{}, error) {
+	items, err := toSlice(list)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+func last(list interface{}) (interface{}, error) {
+	items, err := toSlice(list)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[len(items)-1], nil
+}
+
+func rest(list interface{}) ([]interface{}, error) {
+	items, err := toSlice(list)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[1:], nil
+}
+
+func reverse(list interface{}) ([]interface{}, error) {
+	items, err := toSlice(list)
+	if err != nil {
+		return nil, err
+	}
+	reversed := make([]interface{}, len(items))
+	for i, item := range items {
+		reversed[len(items)-1-i] = item
+	}
+	return reversed, nil
+}
+
+func has(item, list interface{}) (bool, error) {
+	items, err := toSlice(list)
+	if err != nil {
+		return false, err
+	}
+	for _, candidate := range items {
+		if reflect.DeepEqual(candidate, item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// toSlice converts any slice or array into a []interface{}.
+func toSlice(list interface{}) ([]interface{}, error) {
+	if list == nil {
+		return nil, nil
+	}
+
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a list, found %T", list)
+	}
+
+	items := make([]interface{}, value.Len())
+	for i := range items {
+		items[i] = value.Index(i).Interface()
+	}
+	return items, nil
+}
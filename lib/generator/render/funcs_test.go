@@ -0,0 +1,179 @@
+package render
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ttacon/autumn/lib/config"
+)
+
+func TestSplitWords(t *testing.T) {
+	var tests = []struct {
+		in       string
+		expected []string
+	}{
+		{"ResourceFoo", []string{"Resource", "Foo"}},
+		{"resourceFoo", []string{"resource", "Foo"}},
+		{"HTTPServer", []string{"HTTP", "Server"}},
+		{"userID", []string{"user", "ID"}},
+		{"userIDs", []string{"user", "IDs"}},
+		{"user_id", []string{"user", "id"}},
+		{"user-id", []string{"user", "id"}},
+		{"Model2Field", []string{"Model2", "Field"}},
+		{"JSON", []string{"JSON"}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		if actual := SplitWords(test.in); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("SplitWords(%q): expected %q, found %q", test.in, test.expected, actual)
+		}
+	}
+}
+
+func TestNamingFuncs(t *testing.T) {
+	var tests = []struct {
+		fn       string
+		f        func(string) string
+		in       string
+		expected string
+	}{
+		{"camelCase", CamelCase, "ResourceFoo", "resourceFoo"},
+		{"camelCase", CamelCase, "HTTPServer", "httpServer"},
+		{"camelCase", CamelCase, "user_id", "userID"},
+		{"pascalCase", PascalCase, "resource_foo", "ResourceFoo"},
+		{"pascalCase", PascalCase, "http-server", "HTTPServer"},
+		{"pascalCase", PascalCase, "api_url", "APIURL"},
+		{"pascalCase", PascalCase, "IDs", "IDs"},
+		{"pascalCase", PascalCase, "user_ids", "UserIDs"},
+		{"pascalCase", PascalCase, "apiURLs", "APIURLs"},
+		{"pascalCase", PascalCase, "Bus", "Bus"},
+		{"snakeCase", SnakeCase, "ResourceFoo", "resource_foo"},
+		{"snakeCase", SnakeCase, "HTTPServer", "http_server"},
+		{"snakeCase", SnakeCase, "userIDs", "user_ids"},
+		{"kebabCase", KebabCase, "ResourceFoo", "resource-foo"},
+		{"kebabCase", KebabCase, "HTTPServer", "http-server"},
+		{"lowerFirst", LowerFirst, "ResourceFoo", "resourceFoo"},
+		{"lowerFirst", LowerFirst, "HTTPServer", "hTTPServer"},
+		{"lowerFirst", LowerFirst, "", ""},
+		{"upperFirst", UpperFirst, "resourceFoo", "ResourceFoo"},
+		{"upperFirst", UpperFirst, "", ""},
+		{"pluralize", Pluralize, "ResourceFoo", "ResourceFoos"},
+		{"pluralize", Pluralize, "ResourceCategory", "ResourceCategories"},
+		{"pluralize", Pluralize, "Day", "Days"},
+		{"pluralize", Pluralize, "Box", "Boxes"},
+		{"pluralize", Pluralize, "Address", "Addresses"},
+		{"pluralize", Pluralize, "Match", "Matches"},
+		{"pluralize", Pluralize, "person", "people"},
+		{"pluralize", Pluralize, "TeamPerson", "TeamPeople"},
+		{"pluralize", Pluralize, "Sheep", "Sheep"},
+		{"pluralize", Pluralize, "userID", "userIDs"},
+		{"pluralize", Pluralize, "resource_foo", "resource_foos"},
+		{"pluralize", Pluralize, "Quiz", "Quizzes"},
+		{"pluralize", Pluralize, "Buzz", "Buzzes"},
+		{"pluralize", Pluralize, "House", "Houses"},
+		{"pluralize", Pluralize, "Movie", "Movies"},
+		{"pluralize", Pluralize, "Status", "Statuses"},
+		{"pluralize", Pluralize, "Alias", "Aliases"},
+		{"singularize", Singularize, "ResourceFoos", "ResourceFoo"},
+		{"singularize", Singularize, "ResourceCategories", "ResourceCategory"},
+		{"singularize", Singularize, "Boxes", "Box"},
+		{"singularize", Singularize, "Addresses", "Address"},
+		{"singularize", Singularize, "Address", "Address"},
+		{"singularize", Singularize, "Status", "Status"},
+		{"singularize", Singularize, "people", "person"},
+		{"singularize", Singularize, "Knives", "Knife"},
+		{"singularize", Singularize, "userIDs", "userID"},
+		{"singularize", Singularize, "Houses", "House"},
+		{"singularize", Singularize, "Causes", "Cause"},
+		{"singularize", Singularize, "Uses", "Use"},
+		{"singularize", Singularize, "Statuses", "Status"},
+		{"singularize", Singularize, "Buses", "Bus"},
+		{"singularize", Singularize, "Movies", "Movie"},
+		{"singularize", Singularize, "Cookies", "Cookie"},
+		{"singularize", Singularize, "Ties", "Tie"},
+		{"singularize", Singularize, "Flies", "Fly"},
+		{"singularize", Singularize, "Quizzes", "Quiz"},
+		{"singularize", Singularize, "Buzzes", "Buzz"},
+		{"singularize", Singularize, "Sizes", "Size"},
+		{"singularize", Singularize, "Waltzes", "Waltz"},
+		{"singularize", Singularize, "Responses", "Response"},
+		{"singularize", Singularize, "Aliases", "Alias"},
+		{"singularize", Singularize, "Caches", "Cache"},
+		{"singularize", Singularize, "Matches", "Match"},
+		{"singularize", Singularize, "IDs", "ID"},
+	}
+
+	for _, test := range tests {
+		if actual := test.f(test.in); actual != test.expected {
+			t.Errorf("%s(%q): expected %q, found %q", test.fn, test.in, test.expected, actual)
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	var tests = []struct {
+		name     string
+		template string
+		helpers  map[string][]byte
+		expected string
+	}{
+		{
+			name:     "pipelines",
+			template: `{{ .Name | pluralize | kebabCase | printf "/%s" }}`,
+			expected: "/resource-foos",
+		},
+		{
+			name:     "no html escaping",
+			template: `{{ quote .Name }}`,
+			expected: `"ResourceFoo"`,
+		},
+		{
+			name:     "lists",
+			template: `{{ $l := list "a" "b" "c" }}{{ first $l }}{{ last $l }}{{ join "," (reverse $l) }}{{ has "b" $l }}`,
+			expected: "acc,b,atrue",
+		},
+		{
+			name:     "strings",
+			template: `{{ trimPrefix "Resource" .Name | lower }} {{ split "o" .Name | join "0" }}`,
+			expected: "foo Res0urceF00",
+		},
+		{
+			name:     "helpers",
+			template: `{{ routePath .Name }} {{ nested .Name }} {{ pair "a" "b" }}`,
+			helpers: map[string][]byte{
+				"routePath": []byte(`/{{ pluralize . | kebabCase }}`),
+				"nested":    []byte(`{{ routePath . }}/{id}`),
+				"pair":      []byte(`{{ index . 0 }}={{ index . 1 }}`),
+			},
+			expected: "/resource-foos /resource-foos/{id} a=b",
+		},
+	}
+
+	for _, test := range tests {
+		framework := config.NewFramework().AddTemplate("Test", []byte(test.template))
+		for name, helper := range test.helpers {
+			framework.AddHelper(name, helper)
+		}
+
+		var buf = bytes.NewBuffer(nil)
+		if err := Execute(buf, framework, "Test", map[string]interface{}{
+			"Name": "ResourceFoo",
+		}); err != nil {
+			t.Errorf("%s: unexpected err: %v", test.name, err)
+		} else if buf.String() != test.expected {
+			t.Errorf("%s: expected %q, found %q", test.name, test.expected, buf.String())
+		}
+	}
+}
+
+func TestExecuteInvalidHelper(t *testing.T) {
+	framework := config.NewFramework().
+		AddTemplate("Test", []byte(`{{ .Name }}`)).
+		AddHelper("not-valid", []byte(`{{ . }}`))
+
+	if err := Execute(bytes.NewBuffer(nil), framework, "Test", nil); err == nil {
+		t.Error("expected an error for an invalid helper name")
+	}
+}
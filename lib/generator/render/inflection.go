@@ -0,0 +1,230 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+)
+
+// irregularPlurals maps singular words to their plural form for the English
+// words that don't follow any of the rules in Pluralize.
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"person": "people",
+	"man":    "men",
+	"woman":  "women",
+	"mouse":  "mice",
+	"goose":  "geese",
+	"foot":   "feet",
+	"tooth":  "teeth",
+	"ox":     "oxen",
+	"leaf":   "leaves",
+	"life":   "lives",
+	"knife":  "knives",
+	"wife":   "wives",
+	"half":   "halves",
+	"wolf":   "wolves",
+	"shelf":  "shelves",
+	"calf":   "calves",
+	"hero":   "heroes",
+	"potato": "potatoes",
+	"tomato": "tomatoes",
+	"index":  "indices",
+	"matrix": "matrices",
+	"vertex": "vertices",
+	"medium": "media",
+	"quiz":   "quizzes",
+}
+
+// regularPlurals maps singular words to their plural form for the words that
+// Pluralize inflects correctly, but whose plural Singularize's suffix rules
+// would take for the plural of another word, e.g. movies for movy rather than
+// movie.
+var regularPlurals = map[string]string{
+	"alias":     "aliases",
+	"atlas":     "atlases",
+	"avalanche": "avalanches",
+	"bias":      "biases",
+	"brownie":   "brownies",
+	"cache":     "caches",
+	"calorie":   "calories",
+	"canvas":    "canvases",
+	"cookie":    "cookies",
+	"gas":       "gases",
+	"genie":     "genies",
+	"goalie":    "goalies",
+	"headache":  "headaches",
+	"movie":     "movies",
+	"mustache":  "mustaches",
+	"niche":     "niches",
+	"pixie":     "pixies",
+	"prairie":   "prairies",
+	"rookie":    "rookies",
+	"selfie":    "selfies",
+	"smoothie":  "smoothies",
+	"zombie":    "zombies",
+}
+
+// irregularSingulars is the inverse of irregularPlurals and regularPlurals.
+var irregularSingulars = func() map[string]string {
+	singulars := make(map[string]string, len(irregularPlurals)+len(regularPlurals))
+	for _, plurals := range []map[string]string{irregularPlurals, regularPlurals} {
+		for singular, plural := range plurals {
+			singulars[plural] = singular
+		}
+	}
+	return singulars
+}()
+
+// uncountables are words that are the same in their singular and plural
+// forms.
+var uncountables = map[string]bool{
+	"data":        true,
+	"equipment":   true,
+	"fish":        true,
+	"information": true,
+	"metadata":    true,
+	"money":       true,
+	"news":        true,
+	"series":      true,
+	"sheep":       true,
+	"species":     true,
+}
+
+// Pluralize returns the plural form of the last word of the identifier s,
+// preserving the case of the rest of the identifier:
+//
+//	ResourceFoo      -> ResourceFoos
+//	ResourceCategory -> ResourceCategories
+//	ResourceBox      -> ResourceBoxes
+//	person           -> people
+//
+// Irregular and uncountable words are looked up in a small dictionary,
+// everything else follows the regular English rules.
+func Pluralize(s string) string {
+	return inflectLastWord(s, true)
+}
+
+// Singularize returns the singular form of the last word of the identifier
+// s, it is the inverse of Pluralize. Words that already look singular (e.g.
+// Status, Address) are returned unchanged.
+func Singularize(s string) string {
+	return inflectLastWord(s, false)
+}
+
+// inflectLastWord pluralizes or singularizes the lower cased last word of s,
+// and then restores the original capitalization of that word. Acronyms are
+// inflected with a lower case "s" (ID <-> IDs).
+func inflectLastWord(s string, plural bool) string {
+	words := SplitWords(s)
+	if len(words) == 0 {
+		return s
+	}
+
+	word := words[len(words)-1]
+	idx := strings.LastIndex(s, word)
+
+	var inflected string
+	switch {
+	case len(word) > 1 && isUpper(word):
+		inflected = word
+		if plural {
+			inflected += "s"
+		}
+	case len(word) > 2 && isUpper(word[:len(word)-1]) && strings.HasSuffix(word, "s"):
+		inflected = word
+		if !plural {
+			inflected = word[:len(word)-1]
+		}
+	case plural:
+		inflected = pluralizeWord(strings.ToLower(word))
+	default:
+		inflected = singularizeWord(strings.ToLower(word))
+	}
+
+	if unicode.IsUpper([]rune(word)[0]) {
+		inflected = UpperFirst(inflected)
+	}
+
+	return s[:idx] + inflected + s[idx+len(word):]
+}
+
+func isUpper(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) && !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func pluralizeWord(word string) string {
+	if uncountables[word] {
+		return word
+	} else if plural, ok := irregularPlurals[word]; ok {
+		return plural
+	} else if plural, ok := regularPlurals[word]; ok {
+		return plural
+	} else if _, ok := irregularSingulars[word]; ok {
+		// Already plural.
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "s"),
+		strings.HasSuffix(word, "x"),
+		strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"),
+		strings.HasSuffix(word, "sh"):
+		return word + "es"
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !isVowel(word[len(word)-2]):
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+func singularizeWord(word string) string {
+	if uncountables[word] {
+		return word
+	} else if singular, ok := irregularSingulars[word]; ok {
+		return singular
+	} else if _, ok := irregularPlurals[word]; ok {
+		// Already singular.
+		return word
+	} else if _, ok := regularPlurals[word]; ok {
+		// Already singular.
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ss"),
+		strings.HasSuffix(word, "us"),
+		strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		// Short words are plurals of words ending in "ie" (ties, pies).
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"),
+		strings.HasSuffix(word, "xes"),
+		strings.HasSuffix(word, "zzes"),
+		strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "shes"),
+		// statuses, buses but not houses, causes.
+		strings.HasSuffix(word, "uses") && followsConsonant(word, "uses"),
+		// waltzes but not sizes, prizes.
+		strings.HasSuffix(word, "zes") && followsConsonant(word, "zes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "s"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// followsConsonant reports whether the suffix of word follows a consonant.
+func followsConsonant(word, suffix string) bool {
+	idx := len(word) - len(suffix) - 1
+	return idx >= 0 && !isVowel(word[idx])
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) >= 0
+}
@@ -0,0 +1,147 @@
+// Package render executes framework templates, providing them with a common
+// library of functions.
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/template"
+	"unicode"
+
+	"github.com/ttacon/autumn/lib/config"
+)
+
+var (
+	ErrNoSuchTemplate = errors.New("no such template")
+)
+
+// Execute renders the named template of the framework to w, with vars as the
+// template's data. The framework's partials are parsed into the same template
+// set so that the template can call them with `{{template "name" .}}`.
+func Execute(
+	w io.Writer,
+	framework config.Framework,
+	name string,
+	vars interface{},
+) error {
+	templRaw, ok := framework.GetTemplate(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoSuchTemplate, name)
+	}
+
+	funcs, err := Funcs(framework)
+	if err != nil {
+		return err
+	}
+
+	templ := template.New(name).Funcs(funcs)
+	partials := framework.GetPartials()
+	for _, partialName := range sortedKeys(partials) {
+		if _, err := templ.New(partialName).Parse(string(partials[partialName])); err != nil {
+			return fmt.Errorf("failed to parse partial %q: %w", partialName, err)
+		}
+	}
+
+	if _, err := templ.Parse(string(templRaw)); err != nil {
+		return err
+	}
+	return templ.Execute(w, vars)
+}
+
+// Mentions reports whether the named template of the framework, or any of the
+// partials and helpers that it could call, mentions the word, e.g. the
+// `Relationships` of a model, so that data that is costly to compute is only
+// given to the templates that could use it.
+func Mentions(framework config.Framework, name, word string) bool {
+	templRaw, _ := framework.GetTemplate(name)
+	if bytes.Contains(templRaw, []byte(word)) {
+		return true
+	}
+	for _, sources := range []map[string][]byte{framework.GetPartials(), framework.GetHelpers()} {
+		for _, source := range sources {
+			if bytes.Contains(source, []byte(word)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Funcs returns the functions available to templates of the given framework:
+// the library from FuncMap along with the framework's own helpers.
+func Funcs(framework config.Framework) (template.FuncMap, error) {
+	funcs := FuncMap()
+
+	helpers := framework.GetHelpers()
+	if len(helpers) == 0 {
+		return funcs, nil
+	}
+
+	// Helpers are parsed into a single set so that they can call each
+	// other.
+	var (
+		set   = template.New("helpers")
+		names = sortedKeys(helpers)
+	)
+	for _, name := range names {
+		if !isIdentifier(name) {
+			return nil, fmt.Errorf("invalid helper name %q", name)
+		}
+		funcs[name] = helperFunc(set, name)
+	}
+
+	set.Funcs(funcs)
+	for _, name := range names {
+		if _, err := set.New(name).Parse(string(helpers[name])); err != nil {
+			return nil, fmt.Errorf("failed to parse helper %q: %w", name, err)
+		}
+	}
+
+	return funcs, nil
+}
+
+// helperFunc returns a template function that executes the named helper.
+func helperFunc(set *template.Template, name string) func(...interface{}) (string, error) {
+	return func(args ...interface{}) (string, error) {
+		var data interface{} = args
+		switch len(args) {
+		case 0:
+			data = nil
+		case 1:
+			data = args[0]
+		}
+
+		var buf = bytes.NewBuffer(nil)
+		if err := set.ExecuteTemplate(buf, name, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order, so that templates are
+// always parsed in the same order.
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isIdentifier reports whether name can be used as a template function name.
+func isIdentifier(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ttacon/autumn/lib/config"
+)
+
+func TestExecutePartials(t *testing.T) {
+	framework := config.NewFramework().
+		AddTemplate("CreateTemplate", []byte(`func Create{{ .Name }}() error { {{ template "wrapErr" . }} }`)).
+		AddTemplate("DeleteTemplate", []byte(`func Delete{{ .Name }}() error { {{ template "wrapErr" . }} }`)).
+		AddPartial("wrapErr", []byte(`return wrap(err, {{ quote .Name }})`))
+
+	var buf = bytes.NewBuffer(nil)
+	for _, name := range []string{"CreateTemplate", "DeleteTemplate"} {
+		if err := Execute(buf, framework, name, map[string]interface{}{
+			"Name": "ResourceFoo",
+		}); err != nil {
+			t.Fatal("unexpected err: ", err)
+		}
+	}
+
+	expected := `func CreateResourceFoo() error { return wrap(err, "ResourceFoo") }` +
+		`func DeleteResourceFoo() error { return wrap(err, "ResourceFoo") }`
+	if buf.String() != expected {
+		t.Errorf("expected %q, found %q", expected, buf.String())
+	}
+
+	// Overriding the partial changes every template that uses it.
+	framework.AddPartial("wrapErr", []byte(`return err`))
+	buf.Reset()
+	if err := Execute(buf, framework, "CreateTemplate", map[string]interface{}{
+		"Name": "ResourceFoo",
+	}); err != nil {
+		t.Fatal("unexpected err: ", err)
+	} else if expected := `func CreateResourceFoo() error { return err }`; buf.String() != expected {
+		t.Errorf("expected %q, found %q", expected, buf.String())
+	}
+}
+
+func TestMentions(t *testing.T) {
+	framework := config.NewFramework().
+		AddTemplate("CreateTemplate", []byte(`{{ range .Relationships }}{{ .ToName }}{{ end }}`)).
+		AddTemplate("ListTemplate", []byte(`{{ template "routes" . }}`)).
+		AddTemplate("DeleteTemplate", []byte(`func Delete{{ .Name }}() {}`))
+
+	if !Mentions(framework, "CreateTemplate", "Relationships") {
+		t.Error("expected CreateTemplate to mention Relationships")
+	}
+	if Mentions(framework, "DeleteTemplate", "Relationships") {
+		t.Error("expected DeleteTemplate not to mention Relationships")
+	}
+
+	// Any partial could be called by the template.
+	framework.AddPartial("routes", []byte(`{{ len .Relationships }}`))
+	if !Mentions(framework, "DeleteTemplate", "Relationships") {
+		t.Error("expected the routes partial to be mentioned for DeleteTemplate")
+	}
+}
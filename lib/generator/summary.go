@@ -0,0 +1,273 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// FileAction is what applying a plan does to a file.
+type FileAction string
+
+const (
+	ActionCreate    FileAction = "create"
+	ActionUpdate    FileAction = "update"
+	ActionUnchanged FileAction = "unchanged"
+)
+
+// Summary is a human oriented view of a plan and what applying it would do
+// to the project.
+type Summary struct {
+	Models     []ModelSummary     `json:"models"`
+	Frameworks []FrameworkSummary `json:"frameworks"`
+	Generators []GeneratorSummary `json:"generators"`
+}
+
+// ModelSummary is a model that was discovered.
+type ModelSummary struct {
+	Name     string `json:"name"`
+	Package  string `json:"package"`
+	Location string `json:"location"`
+}
+
+// FrameworkSummary is a framework in use and the version of it that is used.
+type FrameworkSummary struct {
+	Generator Kind   `json:"generator"`
+	Framework string `json:"framework"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+}
+
+// GeneratorSummary is the files of a single generator and what applying the
+// plan does to each of them.
+type GeneratorSummary struct {
+	Generator Kind          `json:"generator"`
+	Files     []FileSummary `json:"files"`
+}
+
+// FileSummary is a planned file and what applying the plan does to it.
+type FileSummary struct {
+	Path   string     `json:"path"`
+	Action FileAction `json:"action"`
+}
+
+// Count returns the number of files that the given action applies to.
+func (g GeneratorSummary) Count(action FileAction) int {
+	count := 0
+	for _, file := range g.Files {
+		if file.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Summarize summarizes the plan, comparing the planned files with the files
+// in the project at root to determine which are created, updated or
+// unchanged.
+func Summarize(plan *Plan, root fs.FS) (*Summary, error) {
+	summary := &Summary{}
+
+	var (
+		commits = make(map[string]string)
+		byKind  = make(map[Kind][]FileSummary)
+	)
+	for _, model := range plan.Models {
+		summary.Models = append(summary.Models, ModelSummary{
+			Name:     model.Name,
+			Package:  model.Package,
+			Location: model.Location,
+		})
+
+		for _, file := range model.Files {
+			commits[file.Framework] = file.Commit
+
+			action, err := fileAction(root, file)
+			if err != nil {
+				return nil, err
+			}
+			byKind[file.Generator] = append(byKind[file.Generator], FileSummary{
+				Path:   file.OutputPath,
+				Action: action,
+			})
+		}
+	}
+
+	for _, kc := range kindConfigs(plan.Config.config()) {
+		if len(kc.framework) == 0 {
+			continue
+		}
+		summary.Frameworks = append(summary.Frameworks, FrameworkSummary{
+			Generator: kc.kind,
+			Framework: kc.framework,
+			Version:   kc.version,
+			Commit:    commits[kc.framework],
+		})
+		summary.Generators = append(summary.Generators, GeneratorSummary{
+			Generator: kc.kind,
+			Files:     byKind[kc.kind],
+		})
+	}
+
+	return summary, nil
+}
+
+// fileAction determines what applying the planned file does to the file on
+// disk.
+func fileAction(root fs.FS, file FilePlan) (FileAction, error) {
+	existing, err := fs.ReadFile(root, file.OutputPath)
+	if os.IsNotExist(err) {
+		return ActionCreate, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if contentHash(existing) == file.ContentHash {
+		return ActionUnchanged, nil
+	}
+	return ActionUpdate, nil
+}
+
+// Summary formats.
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format, expected one of text, json or markdown")
+)
+
+// WriteSummary writes the summary to w in the given format.
+func WriteSummary(w io.Writer, summary *Summary, format string) error {
+	var buf = bytes.NewBuffer(nil)
+	switch format {
+	case FormatText:
+		writeTextSummary(buf, summary)
+	case FormatMarkdown:
+		writeMarkdownSummary(buf, summary)
+	case FormatJSON:
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	default:
+		return ErrUnknownFormat
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+var actionSymbols = map[FileAction]string{
+	ActionCreate:    "+",
+	ActionUpdate:    "~",
+	ActionUnchanged: "=",
+}
+
+func writeTextSummary(buf *bytes.Buffer, summary *Summary) {
+	fmt.Fprintf(buf, "Models (%d):\n", len(summary.Models))
+	for _, model := range summary.Models {
+		fmt.Fprintf(buf, "  %s.%s (%s)\n", model.Package, model.Name, model.Location)
+	}
+
+	buf.WriteString("\nFrameworks:\n")
+	for _, framework := range summary.Frameworks {
+		fmt.Fprintf(buf, "  %-10s %s\n", framework.Generator, frameworkVersion(framework))
+	}
+
+	var total = make(map[FileAction]int)
+	for _, gen := range summary.Generators {
+		fmt.Fprintf(
+			buf,
+			"\n%s: %d to create, %d to update, %d unchanged\n",
+			gen.Generator,
+			gen.Count(ActionCreate),
+			gen.Count(ActionUpdate),
+			gen.Count(ActionUnchanged),
+		)
+		for _, file := range gen.Files {
+			fmt.Fprintf(buf, "  %s %s\n", actionSymbols[file.Action], file.Path)
+			total[file.Action]++
+		}
+	}
+
+	fmt.Fprintf(
+		buf,
+		"\nPlan: %d to create, %d to update, %d unchanged.\n",
+		total[ActionCreate],
+		total[ActionUpdate],
+		total[ActionUnchanged],
+	)
+}
+
+func writeMarkdownSummary(buf *bytes.Buffer, summary *Summary) {
+	buf.WriteString("### autumn plan\n\n")
+
+	fmt.Fprintf(buf, "#### Models (%d)\n\n", len(summary.Models))
+	if len(summary.Models) > 0 {
+		buf.WriteString("| Model | Package | Location |\n|---|---|---|\n")
+		for _, model := range summary.Models {
+			fmt.Fprintf(buf, "| `%s` | `%s` | `%s` |\n", model.Name, model.Package, model.Location)
+		}
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("#### Frameworks\n\n")
+	if len(summary.Frameworks) > 0 {
+		buf.WriteString("| Generator | Framework |\n|---|---|\n")
+		for _, framework := range summary.Frameworks {
+			fmt.Fprintf(buf, "| %s | `%s` |\n", framework.Generator, frameworkVersion(framework))
+		}
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("#### Files\n\n")
+	buf.WriteString("| Generator | Create | Update | Unchanged |\n|---|---|---|---|\n")
+	for _, gen := range summary.Generators {
+		fmt.Fprintf(
+			buf,
+			"| %s | %d | %d | %d |\n",
+			gen.Generator,
+			gen.Count(ActionCreate),
+			gen.Count(ActionUpdate),
+			gen.Count(ActionUnchanged),
+		)
+	}
+
+	for _, gen := range summary.Generators {
+		var changed []string
+		for _, file := range gen.Files {
+			if file.Action != ActionUnchanged {
+				changed = append(changed, fmt.Sprintf("- %s `%s`", file.Action, file.Path))
+			}
+		}
+		if len(changed) > 0 {
+			fmt.Fprintf(buf, "\n<details><summary>%s changes</summary>\n\n%s\n\n</details>\n", gen.Generator, strings.Join(changed, "\n"))
+		}
+	}
+}
+
+// frameworkVersion formats a framework along with its version and commit,
+// e.g. `example.com/service@v1.2.0 (0123abc)`.
+func frameworkVersion(framework FrameworkSummary) string {
+	version := framework.Framework
+	if len(framework.Version) > 0 {
+		version += "@" + framework.Version
+	}
+	if len(framework.Commit) > 0 {
+		commit := framework.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		version += " (" + commit + ")"
+	}
+	return version
+}
@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ttacon/autumn/lib/config"
+)
+
+func testSummaryPlan() *Plan {
+	return &Plan{
+		Config: NewPlanConfig(config.Config{
+			Service: config.ServiceConfig{
+				FrameworkInfo: config.FrameworkInfo{
+					Module:  "example.com/service",
+					Version: "v1.2.0",
+				},
+			},
+		}),
+		Models: []ModelPlan{
+			{
+				Name:     "User",
+				Package:  "models",
+				Location: "models/user.go:4:6",
+				Files: []FilePlan{{
+					Generator:   KindService,
+					Framework:   "example.com/service",
+					Commit:      "0123456789abcdef",
+					OutputPath:  "services/user.go",
+					ContentHash: contentHash([]byte("user")),
+				}},
+			},
+			{
+				Name:     "Account",
+				Package:  "models",
+				Location: "models/account.go:4:6",
+				Files: []FilePlan{{
+					Generator:   KindService,
+					Framework:   "example.com/service",
+					Commit:      "0123456789abcdef",
+					OutputPath:  "services/account.go",
+					ContentHash: contentHash([]byte("account")),
+				}},
+			},
+			{
+				Name:     "Order",
+				Package:  "models",
+				Location: "models/order.go:4:6",
+				Files: []FilePlan{{
+					Generator:   KindService,
+					Framework:   "example.com/service",
+					Commit:      "0123456789abcdef",
+					OutputPath:  "services/order.go",
+					ContentHash: contentHash([]byte("order")),
+				}},
+			},
+		},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	root := fstest.MapFS{
+		"services/user.go":    &fstest.MapFile{Data: []byte("user")},
+		"services/account.go": &fstest.MapFile{Data: []byte("old account")},
+	}
+
+	summary, err := Summarize(testSummaryPlan(), root)
+	if err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSummary(&buf, summary, FormatText); err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+
+	expected := `Models (3):
+  models.User (models/user.go:4:6)
+  models.Account (models/account.go:4:6)
+  models.Order (models/order.go:4:6)
+
+Frameworks:
+  service    example.com/service@v1.2.0 (0123456)
+
+service: 1 to create, 1 to update, 1 unchanged
+  = services/user.go
+  ~ services/account.go
+  + services/order.go
+
+Plan: 1 to create, 1 to update, 1 unchanged.
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\nfound:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteSummary(&buf, summary, FormatMarkdown); err != nil {
+		t.Fatal("unexpected err: ", err)
+	}
+	for _, expected := range []string{
+		"| `User` | `models` | `models/user.go:4:6` |",
+		"| service | `example.com/service@v1.2.0 (0123456)` |",
+		"| service | 1 | 1 | 1 |",
+		"- create `services/order.go`",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected markdown to contain %q, found:\n%s", expected, buf.String())
+		}
+	}
+
+	if err := WriteSummary(&buf, summary, "yaml"); err != ErrUnknownFormat {
+		t.Error("expected ErrUnknownFormat, found: ", err)
+	}
+}